@@ -3,7 +3,9 @@ package api
 import (
 	"codelearn-backend/controllers"
 	"codelearn-backend/middlewares"
+	"codelearn-backend/models"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +22,8 @@ func SetupRouter() *gin.Engine {
 		})
 	})
 
+	r.GET("/.well-known/jwks.json", controllers.JWKSHandler)
+
 	api := r.Group("/api/v1")
 	{
 		auth := api.Group("/auth")
@@ -27,25 +31,77 @@ func SetupRouter() *gin.Engine {
 			auth.POST("/register", controllers.RegisterHandler)
 			auth.POST("/login", controllers.LoginHandler)
 			auth.POST("/refresh", controllers.RefreshTokenHandler)
+			auth.POST("/logout", middlewares.AuthMiddleware(), controllers.LogoutHandler)
+			auth.POST("/logout-all", middlewares.AuthMiddleware(), controllers.LogoutAllHandler)
 		}
 
+		api.GET("/languages", controllers.GetLanguagesHandler)
+
 		protected := api.Group("/")
 		protected.Use(middlewares.AuthMiddleware())
 		{
 			protected.GET("/profile", controllers.GetProfileHandler)
 			protected.PUT("/profile", controllers.UpdateProfileHandler)
 
+			protected.GET("/sessions", controllers.GetSessionsHandler)
+			protected.DELETE("/sessions/:id", controllers.RevokeSessionHandler)
+
 			protected.GET("/challenges", controllers.GetChallengesHandler)
+			protected.GET("/tags", controllers.GetTagsHandler)
 			protected.GET("/challenges/:id", controllers.GetChallengeHandler)
+			protected.POST("/challenges", middlewares.RequireRole(models.RoleAuthor, models.RoleAdmin), controllers.CreateChallengeHandler)
+			protected.PUT("/challenges/:id", middlewares.RequireRole(models.RoleAuthor, models.RoleAdmin), controllers.UpdateChallengeHandler)
+			protected.DELETE("/challenges/:id", middlewares.RequireRole(models.RoleAuthor, models.RoleAdmin), controllers.DeleteChallengeHandler)
+			protected.GET("/challenges/:id/revisions", middlewares.RequireRole(models.RoleAuthor, models.RoleAdmin), controllers.GetRevisionsHandler)
+			protected.GET("/challenges/:id/revisions/diff", middlewares.RequireRole(models.RoleAuthor, models.RoleAdmin), controllers.DiffRevisionsHandler)
+			protected.GET("/challenges/:id/revisions/:revision", middlewares.RequireRole(models.RoleAuthor, models.RoleAdmin), controllers.GetRevisionHandler)
+			protected.GET("/challenges/:id/export", middlewares.RequireRole(models.RoleAuthor, models.RoleAdmin), controllers.ExportChallengeHandler)
 			protected.POST("/challenges/:id/submit", controllers.SubmitSolutionHandler)
+			protected.POST("/challenges/:id/run", middlewares.RateLimitMiddleware(10, time.Minute), controllers.RunCodeHandler)
 
 			protected.GET("/submissions", controllers.GetSubmissionsHandler)
 			protected.GET("/submissions/:id", controllers.GetSubmissionHandler)
+			protected.GET("/submissions/:id/events", controllers.SubmissionEventsHandler)
 
 			protected.GET("/leaderboard", controllers.GetLeaderboardHandler)
 
+			protected.GET("/contests", controllers.GetContestsHandler)
+			protected.GET("/contests/:id", controllers.GetContestHandler)
+			protected.POST("/contests", middlewares.RequireRole(models.RoleAuthor, models.RoleAdmin), controllers.CreateContestHandler)
+			protected.PUT("/contests/:id", middlewares.RequireRole(models.RoleAuthor, models.RoleAdmin), controllers.UpdateContestHandler)
+			protected.POST("/contests/:id/register", controllers.RegisterContestHandler)
+			protected.DELETE("/contests/:id/register", controllers.UnregisterContestHandler)
+			protected.POST("/contests/:id/virtual", controllers.StartVirtualContestHandler)
+			protected.GET("/contests/:id/scoreboard", controllers.GetScoreboardHandler)
+			protected.GET("/contests/:id/challenges/:challenge_id", controllers.GetContestChallengeHandler)
+			protected.POST("/contests/:id/challenges/:challenge_id/submit", controllers.SubmitContestSolutionHandler)
+
+			protected.GET("/classrooms", controllers.GetClassroomsHandler)
+			protected.POST("/classrooms", middlewares.RequireRole(models.RoleAuthor, models.RoleAdmin), controllers.CreateClassroomHandler)
+			protected.POST("/classrooms/join", controllers.JoinClassroomHandler)
+			protected.GET("/classrooms/:id", controllers.GetClassroomHandler)
+			protected.GET("/classrooms/:id/members", controllers.GetClassroomMembersHandler)
+			protected.GET("/classrooms/:id/assignments", controllers.GetAssignmentsHandler)
+			protected.POST("/classrooms/:id/assignments", controllers.CreateAssignmentHandler)
+			protected.GET("/classrooms/:id/assignments/:assignment_id", controllers.GetAssignmentHandler)
+			protected.PUT("/classrooms/:id/assignments/:assignment_id", controllers.UpdateAssignmentHandler)
+			protected.GET("/classrooms/:id/assignments/:assignment_id/results", controllers.GetAssignmentResultsHandler)
+
 			protected.POST("/cli/auth", controllers.CLIAuthHandler)
 		}
+
+		admin := api.Group("/admin")
+		admin.Use(middlewares.AuthMiddleware(), middlewares.RequireRole(models.RoleAdmin))
+		{
+			admin.PUT("/users/:id/role", controllers.UpdateUserRoleHandler)
+
+			admin.POST("/challenges/import", controllers.ImportChallengeHandler)
+			admin.POST("/challenges/:id/rejudge", controllers.RejudgeChallengeHandler)
+			admin.POST("/submissions/:id/rejudge", controllers.RejudgeSubmissionHandler)
+			admin.GET("/rejudges/:id", controllers.GetRejudgeHandler)
+
+			admin.POST("/stats/rebuild", controllers.RebuildStatsHandler)
+		}
 	}
 
 	return r
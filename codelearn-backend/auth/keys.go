@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// defaultKeyID is the kid of the JWT_SECRET_KEY key unless
+// JWT_SECRET_KEY_ID names another.
+const defaultKeyID = "default"
+
+// Key is one signing key. HMAC keys sign and verify with the same secret;
+// for RS256 and EdDSA keys only the public half is needed to verify, and
+// only the public half is published.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// Public returns the public key of an asymmetric key, or nil for HMAC keys.
+func (k *Key) Public() crypto.PublicKey {
+	switch k.verifyKey.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return k.verifyKey
+	}
+	return nil
+}
+
+// KeySet holds the active signing key and every key still accepted for
+// verification, so keys can be rotated without invalidating live tokens.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+	// legacy is the JWT_SECRET_KEY key, which verifies the tokens issued
+	// before kid headers were added whatever its own kid.
+	legacy *Key
+}
+
+func (s *KeySet) lookup(kid string) (*Key, bool) {
+	if kid == "" {
+		return s.legacy, s.legacy != nil
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *KeySet) add(key *Key) error {
+	if _, ok := s.keys[key.ID]; ok {
+		return fmt.Errorf("duplicate key id %q", key.ID)
+	}
+	s.keys[key.ID] = key
+	return nil
+}
+
+// LoadKeySet builds the key set from the environment:
+//
+//   - JWT_SECRET_KEY is an HS256 secret with key ID JWT_SECRET_KEY_ID
+//     ("default" if unset). It also verifies tokens without a kid.
+//   - JWT_PREVIOUS_SECRET_KEYS lists retired HS256 secrets still accepted
+//     for verification, as comma separated kid=secret pairs.
+//   - JWT_PRIVATE_KEY_FILE is an optional PEM encoded RSA or Ed25519 private
+//     key with key ID JWT_PRIVATE_KEY_ID. When set it signs new tokens with
+//     RS256 or EdDSA instead of JWT_SECRET_KEY.
+//   - JWT_PUBLIC_KEY_FILES lists retired asymmetric keys still accepted for
+//     verification, as comma separated kid=path pairs of PEM public keys.
+func LoadKeySet() (*KeySet, error) {
+	s := &KeySet{keys: map[string]*Key{}}
+
+	if secret := os.Getenv("JWT_SECRET_KEY"); secret != "" {
+		id := envOr("JWT_SECRET_KEY_ID", defaultKeyID)
+		key := hmacKey(id, secret)
+		if err := s.add(key); err != nil {
+			return nil, err
+		}
+		s.active, s.legacy = key, key
+	}
+
+	for id, secret := range pairs(os.Getenv("JWT_PREVIOUS_SECRET_KEYS")) {
+		if err := s.add(hmacKey(id, secret)); err != nil {
+			return nil, err
+		}
+	}
+
+	if path := os.Getenv("JWT_PRIVATE_KEY_FILE"); path != "" {
+		id := os.Getenv("JWT_PRIVATE_KEY_ID")
+		if id == "" {
+			return nil, errors.New("JWT_PRIVATE_KEY_ID is required with JWT_PRIVATE_KEY_FILE")
+		}
+		key, err := loadPrivateKey(id, path)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.add(key); err != nil {
+			return nil, err
+		}
+		s.active = key
+	}
+
+	for id, path := range pairs(os.Getenv("JWT_PUBLIC_KEY_FILES")) {
+		key, err := loadPublicKey(id, path)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.active == nil {
+		return nil, errors.New("JWT_SECRET_KEY or JWT_PRIVATE_KEY_FILE must be set")
+	}
+	return s, nil
+}
+
+func hmacKey(id, secret string) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, signKey: []byte(secret), verifyKey: []byte(secret)}
+}
+
+func loadPrivateKey(id, path string) (*Key, error) {
+	der, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		// openssl genrsa writes PKCS #1 by default.
+		if rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(der); rsaErr == nil {
+			parsed = rsaKey
+		} else {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, signKey: k, verifyKey: k.Public()}, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported key type %T", path, parsed)
+}
+
+func loadPublicKey(id, path string) (*Key, error) {
+	der, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, verifyKey: k}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, verifyKey: k}, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported key type %T", path, parsed)
+}
+
+func readPEM(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+	return block.Bytes, nil
+}
+
+// pairs parses comma separated key=value pairs.
+func pairs(s string) map[string]string {
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && k != "" && v != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
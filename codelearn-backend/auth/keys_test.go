@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePEM(t *testing.T, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testKeys configures the environment with an HS256 secret under its own
+// kid, a retired secret, an active Ed25519 key and a retired RSA key.
+func testKeys(t *testing.T) (*KeySet, ed25519.PrivateKey, *rsa.PrivateKey) {
+	t.Helper()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JWT_SECRET_KEY", "current-secret")
+	t.Setenv("JWT_SECRET_KEY_ID", "hs-2")
+	t.Setenv("JWT_PREVIOUS_SECRET_KEYS", "hs-1=old-secret")
+	t.Setenv("JWT_PRIVATE_KEY_FILE", writePEM(t, "ed.pem", "PRIVATE KEY", edDER))
+	t.Setenv("JWT_PRIVATE_KEY_ID", "ed-1")
+	t.Setenv("JWT_PUBLIC_KEY_FILES", "rsa-1="+writePEM(t, "rsa.pub", "PUBLIC KEY", rsaDER))
+
+	keys, err := LoadKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys, edKey, rsaKey
+}
+
+func testClaims(typ string) *Claims {
+	return &Claims{
+		UserID: 1,
+		Type:   typ,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+}
+
+// signWith signs access token claims with method and key, naming kid in
+// the header unless it is empty.
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, testClaims(TokenTypeAccess))
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	keys, edKey, rsaKey := testKeys(t)
+	edPublic, err := x509.MarshalPKIXPublicKey(edKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"active asymmetric key", signWith(t, jwt.SigningMethodEdDSA, "ed-1", edKey), true},
+		{"current secret by kid", signWith(t, jwt.SigningMethodHS256, "hs-2", []byte("current-secret")), true},
+		{"retired secret", signWith(t, jwt.SigningMethodHS256, "hs-1", []byte("old-secret")), true},
+		{"retired asymmetric key", signWith(t, jwt.SigningMethodRS256, "rsa-1", rsaKey), true},
+		{"no kid uses the current secret whatever its kid", signWith(t, jwt.SigningMethodHS256, "", []byte("current-secret")), true},
+		{"no kid does not fall back to retired secrets", signWith(t, jwt.SigningMethodHS256, "", []byte("old-secret")), false},
+		{"unknown kid", signWith(t, jwt.SigningMethodHS256, "hs-3", []byte("current-secret")), false},
+		{"secret under the wrong kid", signWith(t, jwt.SigningMethodHS256, "hs-1", []byte("current-secret")), false},
+		{"hmac over a public key", signWith(t, jwt.SigningMethodHS256, "ed-1", edPublic), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := keys.Verify(tt.token, TokenTypeAccess)
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("Verify() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	keys, _, _ := testKeys(t)
+
+	signed, err := keys.Sign(testClaims(TokenTypeRefresh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := jwt.NewParser().ParseUnverified(signed, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := token.Header["kid"]; kid != "ed-1" {
+		t.Errorf("kid = %v, want ed-1", kid)
+	}
+	if alg := token.Method.Alg(); alg != "EdDSA" {
+		t.Errorf("alg = %s, want EdDSA", alg)
+	}
+
+	if _, err := keys.Verify(signed, TokenTypeRefresh); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+	if _, err := keys.Verify(signed, TokenTypeAccess); err != ErrInvalidToken {
+		t.Errorf("Verify() of a refresh token as access = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys, edKey, rsaKey := testKeys(t)
+
+	want := []JWK{
+		{
+			Kty: "OKP", Kid: "ed-1", Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+			X: encode(edKey.Public().(ed25519.PublicKey)),
+		},
+		{
+			Kty: "RSA", Kid: "rsa-1", Use: "sig", Alg: "RS256",
+			N: encode(rsaKey.N.Bytes()), E: "AQAB",
+		},
+	}
+	if got := keys.JWKS(); !reflect.DeepEqual(got, want) {
+		t.Errorf("JWKS() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"secret only", map[string]string{"JWT_SECRET_KEY": "s"}, false},
+		{"no key", map[string]string{}, true},
+		{"duplicate kid", map[string]string{"JWT_SECRET_KEY": "s", "JWT_PREVIOUS_SECRET_KEYS": "default=t"}, true},
+		{"private key without kid", map[string]string{"JWT_SECRET_KEY": "s", "JWT_PRIVATE_KEY_FILE": "key.pem"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"JWT_SECRET_KEY", "JWT_SECRET_KEY_ID", "JWT_PREVIOUS_SECRET_KEYS",
+				"JWT_PRIVATE_KEY_FILE", "JWT_PRIVATE_KEY_ID", "JWT_PUBLIC_KEY_FILES"} {
+				t.Setenv(key, tt.env[key])
+			}
+			if _, err := LoadKeySet(); (err != nil) != tt.wantErr {
+				t.Errorf("LoadKeySet() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Package challengepkg reads and writes challenge packages: a directory or
+// zip archive holding a manifest, a markdown statement, test input and
+// output files, starter code, harness templates and a special checker.
+//
+//	challenge.yaml      manifest (challenge.json is accepted as well)
+//	statement.md        description
+//	tests/01.in         input of the first test case
+//	tests/01.out        its expected output
+//	starter/python.py   starter code per language
+//	harness/go.tmpl     harness template per language
+//	checker/checker.py  special checker
+//
+// Files are copied byte for byte, so exporting a challenge and importing
+// the package again yields the same challenge.
+package challengepkg
+
+import "codelearn-backend/models"
+
+// Manifest names used when reading a package, in order of preference.
+var manifestNames = []string{"challenge.yaml", "challenge.yml", "challenge.json"}
+
+const defaultStatement = "statement.md"
+
+// Manifest is challenge.yaml. File paths are relative to the package root.
+type Manifest struct {
+	Title      string   `yaml:"title" json:"title"`
+	Difficulty string   `yaml:"difficulty" json:"difficulty"`
+	Language   string   `yaml:"language" json:"language"`
+	Tags       []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Status     string   `yaml:"status,omitempty" json:"status,omitempty"`
+	// Statement is the markdown description, statement.md by default.
+	Statement string `yaml:"statement,omitempty" json:"statement,omitempty"`
+
+	TimeLimitMS      int `yaml:"time_limit_ms,omitempty" json:"time_limit_ms,omitempty"`
+	MemoryLimitKB    int `yaml:"memory_limit_kb,omitempty" json:"memory_limit_kb,omitempty"`
+	OutputLimitBytes int `yaml:"output_limit_bytes,omitempty" json:"output_limit_bytes,omitempty"`
+	MaxProcesses     int `yaml:"max_processes,omitempty" json:"max_processes,omitempty"`
+
+	ScoringPolicy string       `yaml:"scoring_policy,omitempty" json:"scoring_policy,omitempty"`
+	Checker       *CheckerSpec `yaml:"checker,omitempty" json:"checker,omitempty"`
+
+	Signature *SignatureSpec `yaml:"signature,omitempty" json:"signature,omitempty"`
+	Tests     []TestSpec     `yaml:"tests" json:"tests"`
+
+	// Starter and Harnesses map a language to a file.
+	Starter   map[string]string `yaml:"starter,omitempty" json:"starter,omitempty"`
+	Harnesses map[string]string `yaml:"harnesses,omitempty" json:"harnesses,omitempty"`
+}
+
+// CheckerSpec selects the output checker. File and Language are only used
+// by the special checker.
+type CheckerSpec struct {
+	Name      string  `yaml:"name" json:"name"`
+	Tolerance float64 `yaml:"tolerance,omitempty" json:"tolerance,omitempty"`
+	Language  string  `yaml:"language,omitempty" json:"language,omitempty"`
+	File      string  `yaml:"file,omitempty" json:"file,omitempty"`
+}
+
+type SignatureSpec struct {
+	Function string      `yaml:"function" json:"function"`
+	Params   []ParamSpec `yaml:"params" json:"params"`
+	Returns  string      `yaml:"returns" json:"returns"`
+}
+
+type ParamSpec struct {
+	Name string `yaml:"name" json:"name"`
+	Type string `yaml:"type" json:"type"`
+}
+
+// TestSpec is one test case. Input and Output default to tests/<name>.in
+// and tests/<name>.out.
+type TestSpec struct {
+	Name        string `yaml:"name" json:"name"`
+	Input       string `yaml:"input,omitempty" json:"input,omitempty"`
+	Output      string `yaml:"output,omitempty" json:"output,omitempty"`
+	Hidden      bool   `yaml:"hidden,omitempty" json:"hidden,omitempty"`
+	Weight      int    `yaml:"weight,omitempty" json:"weight,omitempty"`
+	Group       string `yaml:"group,omitempty" json:"group,omitempty"`
+	TimeLimitMS int    `yaml:"time_limit_ms,omitempty" json:"time_limit_ms,omitempty"`
+}
+
+func (t TestSpec) inputFile() string {
+	if t.Input != "" {
+		return t.Input
+	}
+	return "tests/" + t.Name + ".in"
+}
+
+func (t TestSpec) outputFile() string {
+	if t.Output != "" {
+		return t.Output
+	}
+	return "tests/" + t.Name + ".out"
+}
+
+func toSignature(s *SignatureSpec) *models.Signature {
+	if s == nil {
+		return nil
+	}
+	sig := &models.Signature{Function: s.Function, Returns: s.Returns, Params: []models.Param{}}
+	for _, p := range s.Params {
+		sig.Params = append(sig.Params, models.Param(p))
+	}
+	return sig
+}
+
+func fromSignature(sig *models.Signature) *SignatureSpec {
+	if sig == nil {
+		return nil
+	}
+	s := &SignatureSpec{Function: sig.Function, Returns: sig.Returns, Params: []ParamSpec{}}
+	for _, p := range sig.Params {
+		s.Params = append(s.Params, ParamSpec(p))
+	}
+	return s
+}
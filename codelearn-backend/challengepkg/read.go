@@ -0,0 +1,209 @@
+package challengepkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"codelearn-backend/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limits on what a package may contain, so an upload cannot exhaust memory.
+const (
+	MaxFileSize  = 16 << 20
+	MaxTotalSize = 64 << 20
+)
+
+var ErrNoManifest = errors.New("package has no challenge.yaml or challenge.json")
+
+// Package is a decoded challenge package.
+type Package struct {
+	Challenge models.ChallengeSnapshot
+	// Status is the manifest's status, empty if it has none.
+	Status string
+}
+
+// Load reads the package at path, which is either a directory or a zip
+// archive.
+func Load(p string) (*Package, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return Read(os.DirFS(p))
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return ReadZip(data)
+}
+
+// ReadZip reads a zipped package. The manifest may be at the root of the
+// archive or inside a single top-level directory, as zipping a package
+// directory produces.
+func ReadZip(data []byte) (*Package, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var total uint64
+	for _, f := range zr.File {
+		total += f.UncompressedSize64
+		if f.UncompressedSize64 > MaxFileSize || total > MaxTotalSize {
+			return nil, fmt.Errorf("package too large: %s", f.Name)
+		}
+	}
+
+	fsys, err := packageRoot(zr)
+	if err != nil {
+		return nil, err
+	}
+	return Read(fsys)
+}
+
+func packageRoot(fsys fs.FS) (fs.FS, error) {
+	if findManifest(fsys) != "" {
+		return fsys, nil
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		sub, err := fs.Sub(fsys, entries[0].Name())
+		if err != nil {
+			return nil, err
+		}
+		if findManifest(sub) != "" {
+			return sub, nil
+		}
+	}
+	return nil, ErrNoManifest
+}
+
+func findManifest(fsys fs.FS) string {
+	for _, name := range manifestNames {
+		if _, err := fs.Stat(fsys, name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// Read decodes the package rooted at fsys. It only checks that the package
+// is well formed; whether the challenge is valid is up to the caller.
+func Read(fsys fs.FS) (*Package, error) {
+	name := findManifest(fsys)
+	if name == "" {
+		return nil, ErrNoManifest
+	}
+	data, err := readFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if path.Ext(name) == ".json" {
+		err = json.Unmarshal([]byte(data), &m)
+	} else {
+		err = yaml.Unmarshal([]byte(data), &m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	c := models.ChallengeSnapshot{
+		Title:            m.Title,
+		Difficulty:       m.Difficulty,
+		Language:         m.Language,
+		Tags:             m.Tags,
+		TimeLimitMS:      m.TimeLimitMS,
+		MemoryLimitKB:    m.MemoryLimitKB,
+		OutputLimitBytes: m.OutputLimitBytes,
+		MaxProcesses:     m.MaxProcesses,
+		ScoringPolicy:    m.ScoringPolicy,
+		Signature:        toSignature(m.Signature),
+		TestCases:        []models.TestCase{},
+	}
+
+	statement := m.Statement
+	if statement == "" {
+		statement = defaultStatement
+	}
+	if c.Description, err = readFile(fsys, statement); err != nil {
+		return nil, err
+	}
+
+	if m.Checker != nil {
+		c.Checker = m.Checker.Name
+		c.CheckerTolerance = m.Checker.Tolerance
+		c.CheckerLanguage = m.Checker.Language
+		if m.Checker.File != "" {
+			if c.CheckerCode, err = readFile(fsys, m.Checker.File); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i, t := range m.Tests {
+		if t.Name == "" && (t.Input == "" || t.Output == "") {
+			return nil, fmt.Errorf("test %d: name or input and output files required", i+1)
+		}
+		tc := models.TestCase{Position: i, Hidden: t.Hidden, Weight: t.Weight, Group: t.Group, TimeLimitMS: t.TimeLimitMS}
+		if tc.Input, err = readFile(fsys, t.inputFile()); err != nil {
+			return nil, err
+		}
+		if tc.Expected, err = readFile(fsys, t.outputFile()); err != nil {
+			return nil, err
+		}
+		c.TestCases = append(c.TestCases, tc)
+	}
+
+	if c.StarterCode, err = readFiles(fsys, m.Starter); err != nil {
+		return nil, err
+	}
+	if c.Harnesses, err = readFiles(fsys, m.Harnesses); err != nil {
+		return nil, err
+	}
+
+	return &Package{Challenge: c, Status: m.Status}, nil
+}
+
+// readFiles reads the file of each language.
+func readFiles(fsys fs.FS, files map[string]string) (map[string]string, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	contents := map[string]string{}
+	for lang, name := range files {
+		content, err := readFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		contents[lang] = content
+	}
+	return contents, nil
+}
+
+func readFile(fsys fs.FS, name string) (string, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > MaxFileSize {
+		return "", fmt.Errorf("%s: file too large", name)
+	}
+	data, err := fs.ReadFile(fsys, name)
+	return string(data), err
+}
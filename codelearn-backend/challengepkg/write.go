@@ -0,0 +1,130 @@
+package challengepkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"codelearn-backend/judge"
+	"codelearn-backend/models"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Files lays c out as a package, mapping each path to its content. Limits
+// and the test list are always written out, so the package does not depend
+// on the defaults of the server that imports it.
+func Files(c models.ChallengeSnapshot, status string) (map[string][]byte, error) {
+	files := map[string][]byte{defaultStatement: []byte(c.Description)}
+
+	m := Manifest{
+		Title:            c.Title,
+		Difficulty:       c.Difficulty,
+		Language:         c.Language,
+		Tags:             c.Tags,
+		Status:           status,
+		TimeLimitMS:      c.TimeLimitMS,
+		MemoryLimitKB:    c.MemoryLimitKB,
+		OutputLimitBytes: c.OutputLimitBytes,
+		MaxProcesses:     c.MaxProcesses,
+		ScoringPolicy:    c.ScoringPolicy,
+		Signature:        fromSignature(c.Signature),
+		Tests:            []TestSpec{},
+	}
+
+	if c.Checker != "" {
+		m.Checker = &CheckerSpec{Name: c.Checker, Tolerance: c.CheckerTolerance, Language: c.CheckerLanguage}
+		if c.CheckerCode != "" {
+			m.Checker.File = "checker/checker" + extension(c.CheckerLanguage)
+			files[m.Checker.File] = []byte(c.CheckerCode)
+		}
+	}
+
+	// Zero padded names keep the tests in order in a directory listing.
+	width := len(strconv.Itoa(len(c.TestCases)))
+	if width < 2 {
+		width = 2
+	}
+	for i, tc := range c.TestCases {
+		t := TestSpec{
+			Name:        fmt.Sprintf("%0*d", width, i+1),
+			Hidden:      tc.Hidden,
+			Weight:      tc.Weight,
+			Group:       tc.Group,
+			TimeLimitMS: tc.TimeLimitMS,
+		}
+		files[t.inputFile()] = []byte(tc.Input)
+		files[t.outputFile()] = []byte(tc.Expected)
+		m.Tests = append(m.Tests, t)
+	}
+
+	m.Starter = languageFiles(files, "starter", c.StarterCode, extension)
+	m.Harnesses = languageFiles(files, "harness", c.Harnesses, func(string) string { return ".tmpl" })
+
+	var manifest bytes.Buffer
+	enc := yaml.NewEncoder(&manifest)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	files[manifestNames[0]] = manifest.Bytes()
+
+	return files, nil
+}
+
+// languageFiles adds one file per language under dir and returns the
+// manifest entries pointing at them.
+func languageFiles(files map[string][]byte, dir string, contents map[string]string, ext func(string) string) map[string]string {
+	if len(contents) == 0 {
+		return nil
+	}
+	names := map[string]string{}
+	for lang, content := range contents {
+		name := path.Join(dir, lang+ext(lang))
+		files[name] = []byte(content)
+		names[lang] = name
+	}
+	return names
+}
+
+// extension is the source file extension of a language.
+func extension(lang string) string {
+	if runner, ok := judge.Lookup(lang); ok {
+		if ext := path.Ext(runner.FileName()); ext != "" {
+			return ext
+		}
+	}
+	return ".txt"
+}
+
+// WriteZip writes c as a zipped package to w.
+func WriteZip(w io.Writer, c models.ChallengeSnapshot, status string) error {
+	files, err := Files(c, status)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
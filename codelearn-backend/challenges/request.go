@@ -0,0 +1,217 @@
+// Package challenges validates and stores the challenges authors write,
+// for the API handlers and the importer command alike.
+package challenges
+
+import (
+	"codelearn-backend/judge"
+	"codelearn-backend/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Bounds on what authors may configure.
+const (
+	MaxTitleLength   = 200
+	maxTestCases     = 500
+	maxTimeLimitMS   = 10000
+	maxMemoryLimitKB = 1 << 20
+	maxOutputBytes   = 16 << 20
+	maxProcesses     = 256
+	maxTags          = 10
+)
+
+var (
+	identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	tagName    = regexp.MustCompile(`^[a-z0-9][a-z0-9+#.-]{0,31}$`)
+)
+
+// Request is a challenge as authors submit it, through POST and PUT
+// /challenges or as a package. It replaces the whole challenge, test cases
+// included. Zero limits fall back to the defaults of new challenges.
+type Request struct {
+	models.ChallengeSnapshot
+	Status string `json:"status"`
+}
+
+// ApplyDefaults normalizes the request and fills in unset values.
+func (r *Request) ApplyDefaults() {
+	r.Title = strings.TrimSpace(r.Title)
+	r.Tags = NormalizeTags(r.Tags)
+	if r.Status == "" {
+		r.Status = models.ChallengeDraft
+	}
+	if r.TimeLimitMS == 0 {
+		r.TimeLimitMS = 2000
+	}
+	if r.MemoryLimitKB == 0 {
+		r.MemoryLimitKB = 256 << 10
+	}
+	if r.OutputLimitBytes == 0 {
+		r.OutputLimitBytes = 64 << 10
+	}
+	if r.MaxProcesses == 0 {
+		r.MaxProcesses = 64
+	}
+	if r.ScoringPolicy == "" {
+		r.ScoringPolicy = judge.PolicyProportional
+	}
+	if r.Checker == "" {
+		r.Checker = judge.CheckerExact
+	}
+	for i := range r.TestCases {
+		tc := &r.TestCases[i]
+		tc.ID, tc.ChallengeID, tc.Position = 0, 0, i
+		if tc.Weight == 0 {
+			tc.Weight = 1
+		}
+	}
+}
+
+// Validate checks the request, which must have its defaults applied, and
+// returns the first problem found.
+func (r *Request) Validate() error {
+	if r.Title == "" || len(r.Title) > MaxTitleLength {
+		return fmt.Errorf("title must be between 1 and %d characters", MaxTitleLength)
+	}
+	if strings.TrimSpace(r.Description) == "" {
+		return errors.New("description is required")
+	}
+	if !validDifficulty(r.Difficulty) {
+		return fmt.Errorf("difficulty must be one of %s", strings.Join(models.Difficulties, ", "))
+	}
+	if _, ok := judge.Lookup(r.Language); !ok {
+		return errors.New("unsupported language: " + r.Language)
+	}
+	if r.Status != models.ChallengeDraft && r.Status != models.ChallengePublished {
+		return errors.New("status must be draft or published")
+	}
+	if len(r.Tags) > maxTags {
+		return fmt.Errorf("a challenge can have at most %d tags", maxTags)
+	}
+	for _, tag := range r.Tags {
+		if !tagName.MatchString(tag) {
+			return fmt.Errorf("invalid tag %q", tag)
+		}
+	}
+
+	switch {
+	case r.TimeLimitMS < 0 || r.TimeLimitMS > maxTimeLimitMS:
+		return fmt.Errorf("time_limit_ms must be at most %d", maxTimeLimitMS)
+	case r.MemoryLimitKB < 0 || r.MemoryLimitKB > maxMemoryLimitKB:
+		return fmt.Errorf("memory_limit_kb must be at most %d", maxMemoryLimitKB)
+	case r.OutputLimitBytes < 0 || r.OutputLimitBytes > maxOutputBytes:
+		return fmt.Errorf("output_limit_bytes must be at most %d", maxOutputBytes)
+	case r.MaxProcesses < 0 || r.MaxProcesses > maxProcesses:
+		return fmt.Errorf("max_processes must be at most %d", maxProcesses)
+	}
+
+	if !judge.ValidPolicy(r.ScoringPolicy) {
+		return errors.New("unknown scoring policy: " + r.ScoringPolicy)
+	}
+	if !judge.ValidChecker(r.Checker) {
+		return errors.New("unknown checker: " + r.Checker)
+	}
+	if r.CheckerTolerance < 0 {
+		return errors.New("checker_tolerance must not be negative")
+	}
+	if r.Checker == judge.CheckerSpecial {
+		if _, ok := judge.Lookup(r.CheckerLanguage); !ok {
+			return errors.New("unsupported checker language: " + r.CheckerLanguage)
+		}
+		if strings.TrimSpace(r.CheckerCode) == "" {
+			return errors.New("checker_code is required for the special checker")
+		}
+	}
+
+	if r.Signature != nil {
+		if err := validateSignature(*r.Signature); err != nil {
+			return err
+		}
+		if !judge.HasHarness(r.Language) && strings.TrimSpace(r.Harnesses[r.Language]) == "" {
+			return errors.New("signature challenges in " + r.Language + " need a harness")
+		}
+	}
+	for lang := range r.StarterCode {
+		if _, ok := judge.Lookup(lang); !ok {
+			return errors.New("starter code for unsupported language: " + lang)
+		}
+	}
+	for lang := range r.Harnesses {
+		if _, ok := judge.Lookup(lang); !ok {
+			return errors.New("harness for unsupported language: " + lang)
+		}
+	}
+	if len(r.Harnesses) > 0 && r.Signature == nil {
+		return errors.New("harnesses require a signature")
+	}
+
+	if len(r.TestCases) == 0 || len(r.TestCases) > maxTestCases {
+		return fmt.Errorf("a challenge needs between 1 and %d test cases", maxTestCases)
+	}
+	for i, tc := range r.TestCases {
+		if tc.Weight < 0 {
+			return fmt.Errorf("test case %d: weight must not be negative", i+1)
+		}
+		if tc.TimeLimitMS < 0 || tc.TimeLimitMS > maxTimeLimitMS {
+			return fmt.Errorf("test case %d: time_limit_ms must be at most %d", i+1, maxTimeLimitMS)
+		}
+		if r.Signature != nil {
+			var args []json.RawMessage
+			if err := json.Unmarshal([]byte("["+tc.Input+"]"), &args); err != nil || len(args) != len(r.Signature.Params) {
+				return fmt.Errorf("test case %d: input must be %d comma separated JSON values", i+1, len(r.Signature.Params))
+			}
+			if !json.Valid([]byte(tc.Expected)) {
+				return fmt.Errorf("test case %d: expected output must be a JSON value", i+1)
+			}
+		}
+	}
+
+	return nil
+}
+
+// NormalizeTags lowercases and sorts tags and drops empty and duplicate
+// ones.
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func validDifficulty(difficulty string) bool {
+	for _, d := range models.Difficulties {
+		if d == difficulty {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSignature(sig models.Signature) error {
+	if !identifier.MatchString(sig.Function) {
+		return errors.New("signature: invalid function name")
+	}
+	for _, p := range sig.Params {
+		if !identifier.MatchString(p.Name) {
+			return fmt.Errorf("signature: invalid parameter name %q", p.Name)
+		}
+		if !judge.ValidType(p.Type) {
+			return fmt.Errorf("signature: invalid type %q of parameter %s", p.Type, p.Name)
+		}
+	}
+	if !judge.ValidType(sig.Returns) {
+		return fmt.Errorf("signature: invalid return type %q", sig.Returns)
+	}
+	return nil
+}
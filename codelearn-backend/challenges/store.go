@@ -0,0 +1,208 @@
+package challenges
+
+import (
+	"codelearn-backend/challengepkg"
+	"codelearn-backend/db"
+	"codelearn-backend/models"
+	"codelearn-backend/stats"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Save inserts a challenge authored by editorID when id is 0 and otherwise
+// replaces challenge id, test cases included. Either way it records the
+// result as a new revision, all in one transaction. req must be valid.
+func Save(id, editorID int, req Request) (int, error) {
+	signatureJSON := ""
+	if req.Signature != nil {
+		b, err := json.Marshal(req.Signature)
+		if err != nil {
+			return 0, err
+		}
+		signatureJSON = string(b)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Only submissions to published challenges count towards user stats.
+	previousStatus := req.Status
+	args := []interface{}{req.Title, req.Description, req.Difficulty, req.Language, req.Status,
+		req.TimeLimitMS, req.MemoryLimitKB, req.OutputLimitBytes, req.MaxProcesses, req.ScoringPolicy,
+		req.Checker, req.CheckerTolerance, req.CheckerLanguage, req.CheckerCode, signatureJSON}
+
+	if id == 0 {
+		result, err := tx.Exec(`
+			INSERT INTO challenges (title, description, difficulty, language, status,
+				time_limit_ms, memory_limit_kb, output_limit_bytes, max_processes, scoring_policy,
+				checker, checker_tolerance, checker_language, checker_code, signature, author_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, append(args, editorID)...)
+		if err != nil {
+			return 0, err
+		}
+		newID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		id = int(newID)
+	} else {
+		err := tx.QueryRow("SELECT status FROM challenges WHERE id = ?", id).Scan(&previousStatus)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`
+			UPDATE challenges SET title = ?, description = ?, difficulty = ?, language = ?, status = ?,
+				time_limit_ms = ?, memory_limit_kb = ?, output_limit_bytes = ?, max_processes = ?, scoring_policy = ?,
+				checker = ?, checker_tolerance = ?, checker_language = ?, checker_code = ?, signature = ?,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, append(args, id)...)
+		if err != nil {
+			return 0, err
+		}
+		for _, table := range []string{"test_cases", "starter_code", "harnesses", "challenge_tags"} {
+			if _, err := tx.Exec("DELETE FROM "+table+" WHERE challenge_id = ?", id); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := insertTestCases(tx, id, req.TestCases); err != nil {
+		return 0, err
+	}
+	if err := insertTags(tx, id, req.Tags); err != nil {
+		return 0, err
+	}
+	for lang, code := range req.StarterCode {
+		_, err := tx.Exec("INSERT INTO starter_code (challenge_id, language, code) VALUES (?, ?, ?)", id, lang, code)
+		if err != nil {
+			return 0, err
+		}
+	}
+	for lang, template := range req.Harnesses {
+		_, err := tx.Exec("INSERT INTO harnesses (challenge_id, language, template) VALUES (?, ?, ?)", id, lang, template)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := recordRevision(tx, id, editorID, req.ChallengeSnapshot); err != nil {
+		return 0, err
+	}
+	if previousStatus != req.Status {
+		if err := stats.RefreshChallenge(tx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if previousStatus != req.Status {
+		stats.Invalidate()
+	}
+	return id, nil
+}
+
+// insertTags attaches tags to a challenge, creating the ones that do not
+// exist yet.
+func insertTags(tx *sql.Tx, challengeID int, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT INTO challenge_tags (challenge_id, tag_id)
+			SELECT ?, id FROM tags WHERE name = ?
+		`, challengeID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertTestCases(tx *sql.Tx, challengeID int, testCases []models.TestCase) error {
+	for _, tc := range testCases {
+		_, err := tx.Exec(`
+			INSERT INTO test_cases (challenge_id, position, input, expected, hidden, weight, group_name, time_limit_ms)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, challengeID, tc.Position, tc.Input, tc.Expected, tc.Hidden, tc.Weight, tc.Group, tc.TimeLimitMS)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordRevision stores snapshot as the next revision of a challenge.
+func recordRevision(tx *sql.Tx, challengeID, editorID int, snapshot models.ChallengeSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	var revision int
+	err = tx.QueryRow("SELECT COALESCE(MAX(revision), 0) + 1 FROM challenge_revisions WHERE challenge_id = ?",
+		challengeID).Scan(&revision)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO challenge_revisions (challenge_id, revision, editor_id, snapshot)
+		VALUES (?, ?, ?, ?)
+	`, challengeID, revision, editorID, string(data))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE challenges SET revision = ? WHERE id = ?", revision, challengeID)
+	return err
+}
+
+// ErrInvalidPackage wraps the reason an imported challenge was rejected.
+var ErrInvalidPackage = errors.New("invalid challenge package")
+
+// Import creates a challenge authored by editorID from a package,
+// validating it like POST /challenges does.
+func Import(pkg *challengepkg.Package, editorID int) (int, error) {
+	req := Request{ChallengeSnapshot: pkg.Challenge, Status: pkg.Status}
+	req.ApplyDefaults()
+	if err := req.Validate(); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	return Save(0, editorID, req)
+}
+
+// Delete soft deletes a challenge and takes it out of the leaderboard in
+// the same transaction.
+func Delete(id int) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE challenges SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return err
+	}
+	if err := stats.RefreshChallenge(tx, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	stats.Invalidate()
+	return nil
+}
@@ -2,18 +2,70 @@ package main
 
 import (
 	"codelearn-backend/api"
+	"codelearn-backend/auth"
 	"codelearn-backend/db"
+	"codelearn-backend/grader"
+	"codelearn-backend/initialisers"
+	"codelearn-backend/judge"
+	"codelearn-backend/utils"
+	"context"
+	"errors"
 	"log"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 )
 
 func init() {
+	initialisers.LoadEnv()
+
 	if err := db.InitDB(); err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
+	if err := db.CheckSearchIndex(); err != nil {
+		log.Fatal("Failed to initialize database: ", err)
+	}
 }
 
 func main() {
+	if err := auth.Setup(); err != nil {
+		log.Fatal("Failed to load JWT keys:", err)
+	}
+
+	sandbox, isolated, err := judge.SetupSandbox(judge.SandboxConfig{
+		Helper:           os.Getenv("JUDGE_SANDBOX"),
+		UIDBase:          envInt("JUDGE_UID_BASE", 200000),
+		UIDCount:         envInt("JUDGE_UID_COUNT", 1000),
+		Paths:            envList("JUDGE_SANDBOX_PATHS"),
+		Cgroup:           os.Getenv("JUDGE_CGROUP"),
+		AllowUnsandboxed: os.Getenv("JUDGE_ALLOW_UNSANDBOXED") == "1",
+	})
+	if errors.Is(err, judge.ErrNotIsolated) {
+		log.Fatal("Failed to set up the judge sandbox: ", err, "; run the server as root, or set JUDGE_ALLOW_UNSANDBOXED=1 to grade without isolation")
+	}
+	if err != nil {
+		log.Fatal("Failed to set up the judge sandbox:", err)
+	}
+	if isolated {
+		log.Printf("Judge sandbox: %s", sandbox)
+	} else {
+		log.Printf("WARNING: judge sandbox: %s. Submissions can read .env and the database and reach the network; JUDGE_ALLOW_UNSANDBOXED is only meant for development", sandbox)
+	}
+
+	workers := envInt("JUDGE_WORKERS", runtime.NumCPU())
+	queueSize := envInt("JUDGE_QUEUE_SIZE", 100)
+	if err := grader.Start(context.Background(), workers, queueSize); err != nil {
+		log.Fatal("Failed to start grader:", err)
+	}
+
+	if promoted, err := utils.BootstrapAdmin(); errors.Is(err, utils.ErrBootstrapAdminMissing) {
+		log.Printf("BOOTSTRAP_ADMIN user %s does not exist; register it and restart the server", os.Getenv("BOOTSTRAP_ADMIN"))
+	} else if err != nil {
+		log.Fatal("Failed to bootstrap admin:", err)
+	} else if promoted {
+		log.Printf("Promoted %s to admin", os.Getenv("BOOTSTRAP_ADMIN"))
+	}
 
 	r := api.SetupRouter()
 
@@ -25,3 +77,23 @@ func main() {
 	log.Printf("Starting CodeLearn Backend on port %s", port)
 	log.Fatal(r.Run(":" + port))
 }
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// envList splits a comma separated variable, returning nil when it is
+// unset.
+func envList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
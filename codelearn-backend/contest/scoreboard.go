@@ -0,0 +1,189 @@
+// Package contest computes contest scoreboards.
+package contest
+
+import (
+	"codelearn-backend/judge"
+	"codelearn-backend/models"
+	"sort"
+	"time"
+)
+
+// FreezeCutoff is how far into a participant's run the scoreboard freezes,
+// or the whole duration if the contest has no freeze.
+func FreezeCutoff(c models.Contest) time.Duration {
+	freeze := time.Duration(c.FreezeMinutes) * time.Minute
+	if freeze <= 0 || freeze >= c.Duration() {
+		return c.Duration()
+	}
+	return c.Duration() - freeze
+}
+
+// Build ranks participants by their submissions. Times are measured from
+// each participant's own start, so virtual participants are scored as if
+// they had taken part live. With frozen set, the results of submissions
+// made after the freeze cutoff are hidden and counted as pending.
+//
+// Submissions that did not compile or failed inside the judge are not held
+// against anyone and are ignored.
+func Build(c models.Contest, participants []models.ContestParticipant, submissions []models.ContestSubmission, frozen bool) models.Scoreboard {
+	return build(c, participants, submissions, c.StartTime, c.Duration(), frozen)
+}
+
+// Replay is Build as the scoreboard stood elapsed into the contest, for a
+// participant whose virtual run started at start: everyone's submissions
+// count only up to elapsed into their own run, and frozen hides results
+// from the freeze cutoff of that run on.
+func Replay(c models.Contest, participants []models.ContestParticipant, submissions []models.ContestSubmission, start time.Time, elapsed time.Duration, frozen bool) models.Scoreboard {
+	return build(c, participants, submissions, start, min(elapsed, c.Duration()), frozen)
+}
+
+// build ranks the submissions made within limit of each participant's
+// start. The freeze, if any, is reported relative to start.
+func build(c models.Contest, participants []models.ContestParticipant, submissions []models.ContestSubmission, start time.Time, limit time.Duration, frozen bool) models.Scoreboard {
+	board := models.Scoreboard{ContestID: c.ID, Scoring: c.Scoring, Frozen: frozen, Problems: c.Challenges}
+	if frozen {
+		frozenAt := start.Add(FreezeCutoff(c))
+		board.FrozenAt = &frozenAt
+	}
+
+	problems := map[int]int{}
+	for i, ch := range c.Challenges {
+		problems[ch.ChallengeID] = i
+	}
+
+	rows := map[int]*models.ScoreboardRow{}
+	starts := map[int]time.Time{}
+	for _, p := range participants {
+		row := &models.ScoreboardRow{UserID: p.UserID, Username: p.Username, Virtual: p.Virtual}
+		for _, ch := range c.Challenges {
+			row.Problems = append(row.Problems, models.ProblemResult{Label: ch.Label})
+		}
+		rows[p.UserID] = row
+		starts[p.UserID] = p.Start
+	}
+
+	sorted := append([]models.ContestSubmission{}, submissions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt) })
+
+	for _, s := range sorted {
+		row, ok := rows[s.UserID]
+		i, known := problems[s.ChallengeID]
+		if !ok || !known {
+			continue
+		}
+		elapsed := s.CreatedAt.Sub(starts[s.UserID])
+		if elapsed < 0 || elapsed >= limit {
+			continue
+		}
+
+		result := &row.Problems[i]
+		switch {
+		case s.Status == judge.StatusCompileError || s.Status == judge.StatusInternalError:
+			continue
+		case s.Status == judge.StatusPending || s.Status == judge.StatusRunning,
+			frozen && elapsed >= FreezeCutoff(c):
+			if !result.Solved || c.Scoring == models.ContestPoints {
+				result.Pending++
+			}
+			continue
+		}
+
+		minute := int(elapsed / time.Minute)
+		if c.Scoring == models.ContestPoints {
+			result.Attempts++
+			result.Solved = result.Solved || s.Status == judge.StatusPassed
+			if s.Score > result.Score {
+				result.Score = s.Score
+				result.Points = s.Score * c.Challenges[i].Points / judge.MaxScore
+				result.Minute = minute
+			}
+			continue
+		}
+
+		if result.Solved {
+			continue
+		}
+		result.Attempts++
+		if s.Status == judge.StatusPassed {
+			result.Solved = true
+			result.Score = judge.MaxScore
+			result.Points = c.Challenges[i].Points
+			result.Minute = minute
+		}
+	}
+
+	for _, p := range participants {
+		row := rows[p.UserID]
+		for _, result := range row.Problems {
+			if result.Solved {
+				row.Solved++
+			}
+			if c.Scoring == models.ContestPoints {
+				row.Points += result.Points
+				if result.Score > 0 && result.Minute > row.Penalty {
+					row.Penalty = result.Minute
+				}
+			} else if result.Solved {
+				row.Penalty += result.Minute + c.PenaltyMinutes*(result.Attempts-1)
+			}
+		}
+		board.Rows = append(board.Rows, *row)
+	}
+
+	rank(c.Scoring, board.Rows)
+	return board
+}
+
+// rank sorts rows best first and numbers them; rows that tie share a rank.
+// Only live rows count towards ranks: a virtual row gets the rank it would
+// have had and the live rows keep theirs.
+func rank(scoring string, rows []models.ScoreboardRow) {
+	better := func(a, b models.ScoreboardRow) int {
+		if scoring == models.ContestPoints {
+			if a.Points != b.Points {
+				return b.Points - a.Points
+			}
+			return a.Penalty - b.Penalty
+		}
+		if a.Solved != b.Solved {
+			return b.Solved - a.Solved
+		}
+		if a.Penalty != b.Penalty {
+			return a.Penalty - b.Penalty
+		}
+		return lastSolved(a) - lastSolved(b)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if cmp := better(rows[i], rows[j]); cmp != 0 {
+			return cmp < 0
+		}
+		if rows[i].Virtual != rows[j].Virtual {
+			return !rows[i].Virtual
+		}
+		return rows[i].Username < rows[j].Username
+	})
+	live, prev := 0, -1
+	for i := range rows {
+		rows[i].Rank = live + 1
+		if prev >= 0 && better(rows[prev], rows[i]) == 0 {
+			rows[i].Rank = rows[prev].Rank
+		}
+		if !rows[i].Virtual {
+			live++
+			prev = i
+		}
+	}
+}
+
+// lastSolved is the minute of a row's latest accepted problem, the ICPC
+// tie-breaker after penalty time.
+func lastSolved(row models.ScoreboardRow) int {
+	last := 0
+	for _, result := range row.Problems {
+		if result.Solved && result.Minute > last {
+			last = result.Minute
+		}
+	}
+	return last
+}
@@ -0,0 +1,322 @@
+package contest
+
+import (
+	"codelearn-backend/judge"
+	"codelearn-backend/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+// testContest runs for two hours with a freeze for the last 30 minutes.
+func testContest(scoring string) models.Contest {
+	return models.Contest{
+		ID:             1,
+		StartTime:      start,
+		EndTime:        start.Add(2 * time.Hour),
+		Scoring:        scoring,
+		PenaltyMinutes: 20,
+		FreezeMinutes:  30,
+		Challenges: []models.ContestChallenge{
+			{ChallengeID: 1, Label: "A", Points: 100},
+			{ChallengeID: 2, Label: "B", Points: 200},
+		},
+	}
+}
+
+func live(id int, name string) models.ContestParticipant {
+	return models.ContestParticipant{UserID: id, Username: name, Start: start}
+}
+
+func virtual(id int, name string, at time.Time) models.ContestParticipant {
+	return models.ContestParticipant{UserID: id, Username: name, Start: at, Virtual: true}
+}
+
+// sub is a submission made minute minutes after from.
+func sub(user, challenge int, status string, score int, from time.Time, minute int) models.ContestSubmission {
+	return models.ContestSubmission{
+		UserID:      user,
+		ChallengeID: challenge,
+		Status:      status,
+		Score:       score,
+		CreatedAt:   from.Add(time.Duration(minute) * time.Minute),
+	}
+}
+
+type standing struct {
+	Username string
+	Rank     int
+	Solved   int
+	Points   int
+	Penalty  int
+	Pending  int
+}
+
+func standings(board models.Scoreboard) []standing {
+	var got []standing
+	for _, row := range board.Rows {
+		s := standing{Username: row.Username, Rank: row.Rank, Solved: row.Solved, Points: row.Points, Penalty: row.Penalty}
+		for _, p := range row.Problems {
+			s.Pending += p.Pending
+		}
+		got = append(got, s)
+	}
+	return got
+}
+
+func TestBuild(t *testing.T) {
+	const (
+		passed = judge.StatusPassed
+		wrong  = judge.StatusWrongAnswer
+	)
+	alice, bob, carol := live(1, "alice"), live(2, "bob"), live(3, "carol")
+	ghostStart := start.Add(24 * time.Hour)
+	ghost := virtual(4, "ghost", ghostStart)
+
+	tests := []struct {
+		name         string
+		scoring      string
+		participants []models.ContestParticipant
+		submissions  []models.ContestSubmission
+		frozen       bool
+		want         []standing
+	}{
+		{
+			name:         "icpc ranks by solved then penalty",
+			scoring:      models.ContestICPC,
+			participants: []models.ContestParticipant{alice, bob, carol},
+			submissions: []models.ContestSubmission{
+				sub(1, 1, wrong, 0, start, 5),
+				sub(1, 1, passed, 100, start, 10),
+				sub(1, 2, passed, 100, start, 30),
+				sub(1, 1, wrong, 0, start, 40), // after solving: ignored
+				sub(2, 1, passed, 100, start, 20),
+				sub(2, 2, judge.StatusCompileError, 0, start, 22),
+				sub(2, 2, passed, 100, start, 25),
+				sub(3, 1, passed, 100, start, 50),
+			},
+			want: []standing{
+				{Username: "bob", Rank: 1, Solved: 2, Penalty: 45},
+				{Username: "alice", Rank: 2, Solved: 2, Penalty: 60},
+				{Username: "carol", Rank: 3, Solved: 1, Penalty: 50},
+			},
+		},
+		{
+			name:         "ties share a rank",
+			scoring:      models.ContestICPC,
+			participants: []models.ContestParticipant{carol, bob, alice},
+			submissions: []models.ContestSubmission{
+				sub(1, 1, passed, 100, start, 10),
+				sub(2, 1, passed, 100, start, 10),
+			},
+			want: []standing{
+				{Username: "alice", Rank: 1, Solved: 1, Penalty: 10},
+				{Username: "bob", Rank: 1, Solved: 1, Penalty: 10},
+				{Username: "carol", Rank: 3},
+			},
+		},
+		{
+			name:         "icpc breaks penalty ties on the last accepted minute",
+			scoring:      models.ContestICPC,
+			participants: []models.ContestParticipant{alice, bob},
+			submissions: []models.ContestSubmission{
+				sub(1, 1, passed, 100, start, 10),
+				sub(1, 2, passed, 100, start, 50),
+				sub(2, 1, passed, 100, start, 30),
+				sub(2, 2, passed, 100, start, 30),
+			},
+			want: []standing{
+				{Username: "bob", Rank: 1, Solved: 2, Penalty: 60},
+				{Username: "alice", Rank: 2, Solved: 2, Penalty: 60},
+			},
+		},
+		{
+			name:         "points keep the best score and rank by last improvement",
+			scoring:      models.ContestPoints,
+			participants: []models.ContestParticipant{alice, bob, carol},
+			submissions: []models.ContestSubmission{
+				sub(1, 1, wrong, 50, start, 10),
+				sub(1, 2, wrong, 50, start, 20),
+				sub(1, 1, passed, 100, start, 40),
+				sub(1, 1, wrong, 20, start, 45),
+				sub(2, 2, passed, 100, start, 15),
+				sub(2, 1, wrong, 0, start, 16),
+			},
+			want: []standing{
+				{Username: "bob", Rank: 1, Solved: 1, Points: 200, Penalty: 15},
+				{Username: "alice", Rank: 2, Solved: 1, Points: 200, Penalty: 40},
+				{Username: "carol", Rank: 3},
+			},
+		},
+		{
+			name:         "frozen scoreboards hide results after the cutoff",
+			scoring:      models.ContestICPC,
+			participants: []models.ContestParticipant{alice, bob},
+			submissions: []models.ContestSubmission{
+				sub(1, 1, passed, 100, start, 10),
+				sub(1, 2, passed, 100, start, 100),
+				sub(2, 1, wrong, 0, start, 95),
+				sub(2, 2, judge.StatusPending, 0, start, 50),
+			},
+			frozen: true,
+			want: []standing{
+				{Username: "alice", Rank: 1, Solved: 1, Penalty: 10, Pending: 1},
+				{Username: "bob", Rank: 2, Pending: 2},
+			},
+		},
+		{
+			name:         "unfrozen scoreboards show every result",
+			scoring:      models.ContestICPC,
+			participants: []models.ContestParticipant{alice, bob},
+			submissions: []models.ContestSubmission{
+				sub(1, 1, passed, 100, start, 10),
+				sub(1, 2, passed, 100, start, 100),
+				sub(2, 1, wrong, 0, start, 95),
+			},
+			want: []standing{
+				{Username: "alice", Rank: 1, Solved: 2, Penalty: 110},
+				{Username: "bob", Rank: 2},
+			},
+		},
+		{
+			name:         "virtual rows do not move live ranks",
+			scoring:      models.ContestICPC,
+			participants: []models.ContestParticipant{alice, bob, ghost},
+			submissions: []models.ContestSubmission{
+				sub(1, 1, passed, 100, start, 30),
+				sub(2, 1, passed, 100, start, 60),
+				sub(4, 1, passed, 100, ghostStart, 40),
+				sub(4, 2, passed, 100, ghostStart, 130), // after its run: ignored
+			},
+			want: []standing{
+				{Username: "alice", Rank: 1, Solved: 1, Penalty: 30},
+				{Username: "ghost", Rank: 2, Solved: 1, Penalty: 40},
+				{Username: "bob", Rank: 2, Solved: 1, Penalty: 60},
+			},
+		},
+		{
+			name:         "virtual rows tying a live row rank with it",
+			scoring:      models.ContestICPC,
+			participants: []models.ContestParticipant{ghost, alice, bob},
+			submissions: []models.ContestSubmission{
+				sub(1, 1, passed, 100, start, 30),
+				sub(4, 1, passed, 100, ghostStart, 30),
+			},
+			want: []standing{
+				{Username: "alice", Rank: 1, Solved: 1, Penalty: 30},
+				{Username: "ghost", Rank: 1, Solved: 1, Penalty: 30},
+				{Username: "bob", Rank: 2},
+			},
+		},
+		{
+			name:         "submissions outside the contest and to other challenges are ignored",
+			scoring:      models.ContestICPC,
+			participants: []models.ContestParticipant{alice},
+			submissions: []models.ContestSubmission{
+				sub(1, 1, passed, 100, start, -5),
+				sub(1, 1, passed, 100, start, 120),
+				sub(1, 3, passed, 100, start, 10),
+				sub(5, 1, passed, 100, start, 10),
+			},
+			want: []standing{
+				{Username: "alice", Rank: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := Build(testContest(tt.scoring), tt.participants, tt.submissions, tt.frozen)
+			if got := standings(board); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("standings =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if tt.frozen != (board.FrozenAt != nil) {
+				t.Errorf("FrozenAt = %v with frozen %v", board.FrozenAt, tt.frozen)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	alice, bob := live(1, "alice"), live(2, "bob")
+	callerStart := start.Add(24 * time.Hour)
+	caller := virtual(3, "caller", callerStart)
+	submissions := []models.ContestSubmission{
+		sub(1, 1, judge.StatusPassed, 100, start, 10),
+		sub(1, 2, judge.StatusPassed, 100, start, 99),
+		sub(2, 1, judge.StatusPassed, 100, start, 50),
+		sub(2, 2, judge.StatusWrongAnswer, 0, start, 95),
+		sub(3, 1, judge.StatusPassed, 100, callerStart, 20),
+	}
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		frozen  bool
+		want    []standing
+	}{
+		{
+			name:    "later submissions are not shown yet",
+			elapsed: 60 * time.Minute,
+			want: []standing{
+				{Username: "alice", Rank: 1, Solved: 1, Penalty: 10},
+				{Username: "caller", Rank: 2, Solved: 1, Penalty: 20},
+				{Username: "bob", Rank: 2, Solved: 1, Penalty: 50},
+			},
+		},
+		{
+			name:    "the freeze starts at the cutoff of the virtual run",
+			elapsed: 100 * time.Minute,
+			frozen:  true,
+			want: []standing{
+				{Username: "alice", Rank: 1, Solved: 1, Penalty: 10, Pending: 1},
+				{Username: "caller", Rank: 2, Solved: 1, Penalty: 20},
+				{Username: "bob", Rank: 2, Solved: 1, Penalty: 50, Pending: 1},
+			},
+		},
+		{
+			name:    "unfrozen at the same point",
+			elapsed: 100 * time.Minute,
+			want: []standing{
+				{Username: "alice", Rank: 1, Solved: 2, Penalty: 109},
+				{Username: "caller", Rank: 2, Solved: 1, Penalty: 20},
+				{Username: "bob", Rank: 2, Solved: 1, Penalty: 50},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			participants := []models.ContestParticipant{alice, bob, caller}
+			board := Replay(testContest(models.ContestICPC), participants, submissions, callerStart, tt.elapsed, tt.frozen)
+			if got := standings(board); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("standings =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if tt.frozen && !board.FrozenAt.Equal(callerStart.Add(90*time.Minute)) {
+				t.Errorf("FrozenAt = %v, want 90 minutes into the virtual run", board.FrozenAt)
+			}
+		})
+	}
+}
+
+func TestFreezeCutoff(t *testing.T) {
+	tests := []struct {
+		freezeMinutes int
+		want          time.Duration
+	}{
+		{0, 2 * time.Hour},
+		{30, 90 * time.Minute},
+		{120, 2 * time.Hour},
+		{500, 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		c := testContest(models.ContestICPC)
+		c.FreezeMinutes = tt.freezeMinutes
+		if got := FreezeCutoff(c); got != tt.want {
+			t.Errorf("FreezeCutoff with %d minutes = %v, want %v", tt.freezeMinutes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/grader"
+	"codelearn-backend/judge"
+	"codelearn-backend/models"
+	"codelearn-backend/stats"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RejudgeChallengeHandler regrades every finished submission to a challenge
+// against its current test cases.
+func RejudgeChallengeHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid challenge ID"})
+		return
+	}
+
+	var challengeExists bool
+	err = db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM challenges WHERE id = ?)", id).Scan(&challengeExists)
+	if err != nil || !challengeExists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	rejudgeID, err := grader.RejudgeChallenge(id, userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start rejudge"})
+		return
+	}
+
+	respondRejudge(c, rejudgeID)
+}
+
+// RejudgeSubmissionHandler regrades a single finished submission.
+func RejudgeSubmissionHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission ID"})
+		return
+	}
+
+	var status string
+	err = db.DB.QueryRow("SELECT status FROM submissions WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submission"})
+		return
+	}
+	if status == judge.StatusPending || status == judge.StatusRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Submission is still being graded"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	rejudgeID, err := grader.RejudgeSubmission(id, userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start rejudge"})
+		return
+	}
+
+	respondRejudge(c, rejudgeID)
+}
+
+func respondRejudge(c *gin.Context, rejudgeID int) {
+	rejudge, err := loadRejudge(rejudgeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rejudge"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Submissions queued for rejudging",
+		"rejudge": rejudge,
+	})
+}
+
+// GetRejudgeHandler reports the progress of a rejudge along with the
+// previous and new verdict of each submission in it.
+func GetRejudgeHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rejudge ID"})
+		return
+	}
+
+	rejudge, err := loadRejudge(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rejudge not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rejudge"})
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT submission_id, previous_status, previous_score, new_status, new_score, judged_at
+		FROM rejudge_items WHERE rejudge_id = ? ORDER BY submission_id
+	`, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rejudge items"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.RejudgeItem
+		err := rows.Scan(&item.SubmissionID, &item.PreviousStatus, &item.PreviousScore,
+			&item.NewStatus, &item.NewScore, &item.JudgedAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan rejudge item"})
+			return
+		}
+		rejudge.Items = append(rejudge.Items, item)
+	}
+
+	c.JSON(http.StatusOK, rejudge)
+}
+
+func loadRejudge(id int) (*models.Rejudge, error) {
+	var r models.Rejudge
+	err := db.DB.QueryRow(`
+		SELECT r.id, r.challenge_id, r.submission_id, r.requested_by, r.total, r.created_at,
+			COUNT(i.new_status),
+			COALESCE(SUM(i.new_status IS NOT NULL AND (i.new_status != i.previous_status OR i.new_score != i.previous_score)), 0)
+		FROM rejudges r
+		LEFT JOIN rejudge_items i ON i.rejudge_id = r.id
+		WHERE r.id = ?
+		GROUP BY r.id
+	`, id).Scan(&r.ID, &r.ChallengeID, &r.SubmissionID, &r.RequestedBy, &r.Total, &r.CreatedAt,
+		&r.Judged, &r.Changed)
+	if err != nil {
+		return nil, err
+	}
+	r.Finished = r.Judged == r.Total
+	return &r, nil
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdateUserRoleHandler changes a user's role. It applies from the user's
+// next request; the role embedded in their tokens is only refreshed on
+// their next login or token refresh.
+func UpdateUserRoleHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role: " + req.Role})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if id == userID.(int) && req.Role != models.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admins cannot demote themselves"})
+		return
+	}
+
+	result, err := db.DB.Exec("UPDATE users SET role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", req.Role, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully", "role": req.Role})
+}
+
+// RebuildStatsHandler recomputes the materialized leaderboard from the
+// submissions.
+func RebuildStatsHandler(c *gin.Context) {
+	if err := stats.Rebuild(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rebuild stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stats rebuilt successfully"})
+}
@@ -1,25 +1,18 @@
 package controllers
 
 import (
+	"codelearn-backend/auth"
 	"codelearn-backend/db"
 	"codelearn-backend/models"
 	"codelearn-backend/utils"
 	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("your-secret-key-change-in-production")
-
-type Claims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	jwt.RegisteredClaims
-}
-
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Email    string `json:"email" binding:"required,email"`
@@ -72,9 +65,10 @@ func RegisterHandler(c *gin.Context) {
 		ID:       int(userID),
 		Username: req.Username,
 		Email:    req.Email,
+		Role:     models.RoleStudent,
 	}
 
-	token, refreshToken, err := utils.GenerateTokens(user)
+	token, refreshToken, err := utils.GenerateTokens(user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
@@ -97,9 +91,9 @@ func LoginHandler(c *gin.Context) {
 	var user models.User
 	var hashedPassword string
 	err := db.DB.QueryRow(`
-		SELECT id, username, email, password, created_at, updated_at
+		SELECT id, username, email, password, role, created_at, updated_at
 		FROM users WHERE username = ?
-	`, req.Username).Scan(&user.ID, &user.Username, &user.Email, &hashedPassword, &user.CreatedAt, &user.UpdatedAt)
+	`, req.Username).Scan(&user.ID, &user.Username, &user.Email, &hashedPassword, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
@@ -115,7 +109,7 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
-	token, refreshToken, err := utils.GenerateTokens(user)
+	token, refreshToken, err := utils.GenerateTokens(user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
@@ -128,8 +122,36 @@ func LoginHandler(c *gin.Context) {
 	})
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 func RefreshTokenHandler(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "Refresh token not implemented yet"})
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, token, refreshToken, err := utils.RotateRefreshToken(req.RefreshToken)
+	if errors.Is(err, utils.ErrRefreshTokenReused) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token already used; all sessions from this login have been revoked"})
+		return
+	}
+	if errors.Is(err, utils.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         user,
+	})
 }
 
 func GetProfileHandler(c *gin.Context) {
@@ -141,9 +163,9 @@ func GetProfileHandler(c *gin.Context) {
 
 	var user models.User
 	err := db.DB.QueryRow(`
-		SELECT id, username, email, created_at, updated_at
+		SELECT id, username, email, role, created_at, updated_at
 		FROM users WHERE id = ?
-	`, userID).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	`, userID).Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user profile"})
@@ -195,17 +217,16 @@ func CLIAuthHandler(c *gin.Context) {
 		return
 	}
 
-	cliClaims := &Claims{
-		UserID:   userID.(int),
-		Username: username.(string),
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(30 * 24 * time.Hour)), // 30 days
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+	expiresAt := time.Now().Add(30 * 24 * time.Hour) // 30 days
+	sessionID, err := utils.CreateSession(userID.(int), c.Request.UserAgent(), c.ClientIP(), expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create CLI session"})
+		return
 	}
 
-	cliToken := jwt.NewWithClaims(jwt.SigningMethodHS256, cliClaims)
-	cliTokenString, err := cliToken.SignedString(jwtSecret)
+	role, _ := c.Get("role")
+	user := models.User{ID: userID.(int), Username: username.(string), Role: role.(string)}
+	cliTokenString, err := utils.SignToken(user, auth.TokenTypeAccess, sessionID, time.Now(), time.Until(expiresAt))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate CLI token"})
 		return
@@ -215,6 +236,75 @@ func CLIAuthHandler(c *gin.Context) {
 		"cli_token":  cliTokenString,
 		"user_id":    userID,
 		"username":   username,
-		"expires_at": time.Now().Add(30 * 24 * time.Hour).Unix(),
+		"expires_at": expiresAt.Unix(),
+	})
+}
+
+// LogoutHandler ends the session of the token used for the request,
+// invalidating its access and refresh tokens.
+func LogoutHandler(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := utils.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+	if _, err := utils.RevokeSession(claims.UserID, claims.SessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAllHandler ends every session of the user, on all devices.
+func LogoutAllHandler(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := utils.RevokeAllSessions(userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+}
+
+func GetSessionsHandler(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	sessions, err := utils.ListSessions(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"total":    len(sessions),
 	})
 }
+
+func RevokeSessionHandler(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	found, err := utils.RevokeSession(userID.(int), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// JWKSHandler publishes the public keys that verify tokens issued by this
+// server.
+func JWKSHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"keys": auth.JWKS()})
+}
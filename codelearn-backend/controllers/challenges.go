@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"codelearn-backend/challenges"
+	"codelearn-backend/db"
+	"codelearn-backend/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// visibleChallenges is the SQL condition matching the challenges the caller
+// can see: published ones and their own drafts, or every draft for admins.
+// Deleted challenges are never visible.
+func visibleChallenges(c *gin.Context) (string, []interface{}) {
+	if role, _ := c.Get("role"); role == models.RoleAdmin {
+		return "deleted_at IS NULL", nil
+	}
+	userID, _ := c.Get("user_id")
+	return "deleted_at IS NULL AND (status = ? OR author_id = ?)", []interface{}{models.ChallengePublished, userID}
+}
+
+// challengeVisible reports whether the caller can see a challenge.
+func challengeVisible(c *gin.Context, id int) (bool, error) {
+	cond, args := visibleChallenges(c)
+	var visible bool
+	err := db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM challenges WHERE id = ? AND "+cond+")",
+		append([]interface{}{id}, args...)...).Scan(&visible)
+	return visible, err
+}
+
+// canEditChallenge reports whether the caller may modify a challenge by
+// authorID: admins may edit any, authors only their own.
+func canEditChallenge(c *gin.Context, authorID int) bool {
+	role, _ := c.Get("role")
+	userID, _ := c.Get("user_id")
+	return role == models.RoleAdmin || (role == models.RoleAuthor && authorID == userID)
+}
+
+// loadChallenge reads a challenge that has not been deleted with all of its
+// test cases, hidden ones included.
+func loadChallenge(id int) (*models.Challenge, error) {
+	var challenge models.Challenge
+	var signatureJSON string
+	err := db.DB.QueryRow(`
+		SELECT id, title, description, difficulty, language, created_at, updated_at,
+		       COALESCE(author_id, 0), status, revision,
+		       time_limit_ms, memory_limit_kb, output_limit_bytes, max_processes, scoring_policy,
+		       checker, checker_tolerance, signature
+		FROM challenges WHERE id = ? AND deleted_at IS NULL
+	`, id).Scan(&challenge.ID, &challenge.Title, &challenge.Description,
+		&challenge.Difficulty, &challenge.Language, &challenge.CreatedAt, &challenge.UpdatedAt,
+		&challenge.AuthorID, &challenge.Status, &challenge.Revision,
+		&challenge.TimeLimitMS, &challenge.MemoryLimitKB, &challenge.OutputLimitBytes, &challenge.MaxProcesses,
+		&challenge.ScoringPolicy, &challenge.Checker, &challenge.CheckerTolerance, &signatureJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if signatureJSON != "" {
+		challenge.Signature = &models.Signature{}
+		if err := json.Unmarshal([]byte(signatureJSON), challenge.Signature); err != nil {
+			return nil, fmt.Errorf("invalid challenge signature: %w", err)
+		}
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT id, challenge_id, position, input, expected, hidden, weight, group_name, time_limit_ms
+		FROM test_cases WHERE challenge_id = ?
+		ORDER BY position, id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tc models.TestCase
+		err := rows.Scan(&tc.ID, &tc.ChallengeID, &tc.Position, &tc.Input, &tc.Expected,
+			&tc.Hidden, &tc.Weight, &tc.Group, &tc.TimeLimitMS)
+		if err != nil {
+			return nil, err
+		}
+		challenge.TestCases = append(challenge.TestCases, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	starterRows, err := db.DB.Query("SELECT language, code FROM starter_code WHERE challenge_id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer starterRows.Close()
+
+	for starterRows.Next() {
+		var lang, code string
+		if err := starterRows.Scan(&lang, &code); err != nil {
+			return nil, err
+		}
+		if challenge.StarterCode == nil {
+			challenge.StarterCode = map[string]string{}
+		}
+		challenge.StarterCode[lang] = code
+	}
+
+	if err := starterRows.Err(); err != nil {
+		return nil, err
+	}
+
+	tagRows, err := db.DB.Query(`
+		SELECT t.name FROM challenge_tags ct JOIN tags t ON t.id = ct.tag_id
+		WHERE ct.challenge_id = ? ORDER BY t.name
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+
+	for tagRows.Next() {
+		var tag string
+		if err := tagRows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		challenge.Tags = append(challenge.Tags, tag)
+	}
+
+	return &challenge, tagRows.Err()
+}
+
+func CreateChallengeHandler(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req challenges.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.ApplyDefaults()
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := challenges.Save(0, userID.(int), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create challenge"})
+		return
+	}
+
+	challenge, err := loadChallenge(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, challenge)
+}
+
+func UpdateChallengeHandler(c *gin.Context) {
+	id, ok := editableChallenge(c)
+	if !ok {
+		return
+	}
+
+	var req challenges.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.ApplyDefaults()
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if _, err := challenges.Save(id, userID.(int), req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update challenge"})
+		return
+	}
+
+	challenge, err := loadChallenge(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, challenge)
+}
+
+// DeleteChallengeHandler soft deletes a challenge: it disappears from
+// listings and stops accepting submissions, but existing submissions keep
+// referring to it.
+func DeleteChallengeHandler(c *gin.Context) {
+	id, ok := editableChallenge(c)
+	if !ok {
+		return
+	}
+
+	if err := challenges.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete challenge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Challenge deleted successfully"})
+}
+
+// editableChallenge parses the challenge ID of the request and checks the
+// caller may modify it, writing the error response if not.
+func editableChallenge(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid challenge ID"})
+		return 0, false
+	}
+
+	var authorID int
+	err = db.DB.QueryRow("SELECT COALESCE(author_id, 0) FROM challenges WHERE id = ? AND deleted_at IS NULL", id).Scan(&authorID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
+		return 0, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return 0, false
+	}
+
+	if !canEditChallenge(c, authorID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own challenges"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,694 @@
+package controllers
+
+import (
+	"codelearn-backend/challenges"
+	"codelearn-backend/db"
+	"codelearn-backend/judge"
+	"codelearn-backend/models"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Bounds on what instructors may configure.
+const (
+	maxAssignmentChallenges = 50
+	inviteCodeLength        = 8
+)
+
+// inviteAlphabet leaves out characters that are easily confused when an
+// invite code is read out or typed.
+const inviteAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// inviteCodeAttempts is how many invite codes creating a classroom draws
+// before giving up on collisions with existing codes.
+const inviteCodeAttempts = 5
+
+type ClassroomRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type JoinClassroomRequest struct {
+	InviteCode string `json:"invite_code" binding:"required"`
+}
+
+// AssignmentRequest is the body of POST and PUT assignments. LatePolicy
+// defaults to accept; LatePenalty only applies to the penalty policy.
+type AssignmentRequest struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	DueAt       time.Time `json:"due_at"`
+	LatePolicy  string    `json:"late_policy"`
+	LatePenalty int       `json:"late_penalty"`
+	Challenges  []int     `json:"challenges"`
+}
+
+func (r *AssignmentRequest) applyDefaults() {
+	r.Title = strings.TrimSpace(r.Title)
+	if r.LatePolicy == "" {
+		r.LatePolicy = models.LateAccept
+	}
+	if r.LatePolicy != models.LatePenalty {
+		r.LatePenalty = 0
+	}
+}
+
+// validate checks the request. Students submit to assignment challenges
+// like to any other, so they must be published.
+func (r *AssignmentRequest) validate() error {
+	if r.Title == "" || len(r.Title) > challenges.MaxTitleLength {
+		return fmt.Errorf("title must be between 1 and %d characters", challenges.MaxTitleLength)
+	}
+	if r.DueAt.IsZero() {
+		return errors.New("due_at is required")
+	}
+	switch r.LatePolicy {
+	case models.LateAccept, models.LateReject:
+	case models.LatePenalty:
+		if r.LatePenalty < 1 || r.LatePenalty > 100 {
+			return errors.New("late_penalty must be between 1 and 100")
+		}
+	default:
+		return errors.New("late_policy must be accept, penalty or reject")
+	}
+
+	if len(r.Challenges) == 0 || len(r.Challenges) > maxAssignmentChallenges {
+		return fmt.Errorf("an assignment needs between 1 and %d challenges", maxAssignmentChallenges)
+	}
+	seen := map[int]bool{}
+	for _, id := range r.Challenges {
+		if seen[id] {
+			return fmt.Errorf("challenge %d is listed twice", id)
+		}
+		seen[id] = true
+		var published bool
+		err := db.DB.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM challenges WHERE id = ? AND status = ? AND deleted_at IS NULL)
+		`, id, models.ChallengePublished).Scan(&published)
+		if err != nil {
+			return err
+		}
+		if !published {
+			return fmt.Errorf("challenge %d not found or not published", id)
+		}
+	}
+	return nil
+}
+
+func newInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = inviteAlphabet[int(b[i])%len(inviteAlphabet)]
+	}
+	return string(b), nil
+}
+
+// insertClassroom creates a classroom with a fresh invite code, drawing
+// another code while the one drawn is already taken.
+func insertClassroom(name, description string, ownerID int) (int64, error) {
+	for attempt := 1; ; attempt++ {
+		code, err := newInviteCode()
+		if err != nil {
+			return 0, err
+		}
+		result, err := db.DB.Exec(`
+			INSERT INTO classrooms (name, description, owner_id, invite_code) VALUES (?, ?, ?, ?)
+		`, name, description, ownerID, code)
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique &&
+			attempt < inviteCodeAttempts {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return result.LastInsertId()
+	}
+}
+
+// loadClassroom reads a classroom with its number of members and whether
+// userID is one of them.
+func loadClassroom(id, userID int) (*models.Classroom, error) {
+	var cl models.Classroom
+	err := db.DB.QueryRow(`
+		SELECT cl.id, cl.name, cl.description, cl.owner_id, u.username, cl.invite_code, cl.created_at,
+		       (SELECT COUNT(*) FROM classroom_members WHERE classroom_id = cl.id),
+		       EXISTS(SELECT 1 FROM classroom_members WHERE classroom_id = cl.id AND user_id = ?)
+		FROM classrooms cl JOIN users u ON u.id = cl.owner_id
+		WHERE cl.id = ?
+	`, userID, id).Scan(&cl.ID, &cl.Name, &cl.Description, &cl.OwnerID, &cl.Owner, &cl.InviteCode,
+		&cl.CreatedAt, &cl.Members, &cl.Enrolled)
+	if err != nil {
+		return nil, err
+	}
+	return &cl, nil
+}
+
+// canManageClassroom reports whether the caller owns a classroom or is an
+// admin.
+func canManageClassroom(c *gin.Context, cl *models.Classroom) bool {
+	role, _ := c.Get("role")
+	userID, _ := c.Get("user_id")
+	return role == models.RoleAdmin || cl.OwnerID == userID
+}
+
+// classroomFromRequest loads the classroom of the request, writing the
+// error response if it does not exist or the caller is neither a member
+// nor a manager. The invite code is hidden from members.
+func classroomFromRequest(c *gin.Context) (*models.Classroom, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid classroom ID"})
+		return nil, false
+	}
+
+	userID, _ := c.Get("user_id")
+	cl, err := loadClassroom(id, userID.(int))
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch classroom"})
+		return nil, false
+	}
+	if err == sql.ErrNoRows || (!cl.Enrolled && !canManageClassroom(c, cl)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Classroom not found"})
+		return nil, false
+	}
+
+	if !canManageClassroom(c, cl) {
+		cl.InviteCode = ""
+	}
+	return cl, true
+}
+
+// GetClassroomsHandler lists the classrooms the caller owns or is enrolled
+// in.
+func GetClassroomsHandler(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	rows, err := db.DB.Query(`
+		SELECT cl.id, cl.name, cl.description, cl.owner_id, u.username, cl.invite_code, cl.created_at,
+		       (SELECT COUNT(*) FROM classroom_members WHERE classroom_id = cl.id),
+		       EXISTS(SELECT 1 FROM classroom_members WHERE classroom_id = cl.id AND user_id = ?)
+		FROM classrooms cl JOIN users u ON u.id = cl.owner_id
+		WHERE cl.owner_id = ? OR cl.id IN (SELECT classroom_id FROM classroom_members WHERE user_id = ?)
+		ORDER BY cl.created_at DESC, cl.id DESC
+	`, userID, userID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch classrooms"})
+		return
+	}
+	defer rows.Close()
+
+	classrooms := []models.Classroom{}
+	for rows.Next() {
+		var cl models.Classroom
+		err := rows.Scan(&cl.ID, &cl.Name, &cl.Description, &cl.OwnerID, &cl.Owner, &cl.InviteCode,
+			&cl.CreatedAt, &cl.Members, &cl.Enrolled)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan classroom"})
+			return
+		}
+		if cl.OwnerID != userID {
+			cl.InviteCode = ""
+		}
+		classrooms = append(classrooms, cl)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"classrooms": classrooms,
+		"total":      len(classrooms),
+	})
+}
+
+func GetClassroomHandler(c *gin.Context) {
+	cl, ok := classroomFromRequest(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, cl)
+}
+
+// CreateClassroomHandler creates a classroom owned by the caller with a
+// fresh invite code.
+func CreateClassroomHandler(c *gin.Context) {
+	var req ClassroomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > challenges.MaxTitleLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("name must be between 1 and %d characters", challenges.MaxTitleLength)})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	id, err := insertClassroom(req.Name, req.Description, userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create classroom"})
+		return
+	}
+
+	cl, err := loadClassroom(int(id), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch classroom"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, cl)
+}
+
+// JoinClassroomHandler enrolls the caller in the classroom of an invite
+// code.
+func JoinClassroomHandler(c *gin.Context) {
+	var req JoinClassroomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var id, ownerID int
+	err := db.DB.QueryRow("SELECT id, owner_id FROM classrooms WHERE invite_code = ?",
+		strings.ToUpper(strings.TrimSpace(req.InviteCode))).Scan(&id, &ownerID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid invite code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch classroom"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if ownerID == userID {
+		c.JSON(http.StatusConflict, gin.H{"error": "You own this classroom"})
+		return
+	}
+
+	_, err = db.DB.Exec("INSERT OR IGNORE INTO classroom_members (classroom_id, user_id) VALUES (?, ?)", id, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join classroom"})
+		return
+	}
+
+	cl, err := loadClassroom(id, userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch classroom"})
+		return
+	}
+	cl.InviteCode = ""
+
+	c.JSON(http.StatusOK, cl)
+}
+
+// GetClassroomMembersHandler lists the students enrolled in a classroom.
+func GetClassroomMembersHandler(c *gin.Context) {
+	cl, ok := classroomFromRequest(c)
+	if !ok {
+		return
+	}
+	if !canManageClassroom(c, cl) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the instructor can list members"})
+		return
+	}
+
+	members, err := loadMembers(cl.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"members": members,
+		"total":   len(members),
+	})
+}
+
+func loadMembers(classroomID int) ([]models.ClassroomMember, error) {
+	rows, err := db.DB.Query(`
+		SELECT m.user_id, u.username, m.joined_at
+		FROM classroom_members m JOIN users u ON u.id = m.user_id
+		WHERE m.classroom_id = ?
+		ORDER BY u.username
+	`, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.ClassroomMember{}
+	for rows.Next() {
+		var m models.ClassroomMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// loadAssignments reads the assignments of a classroom, or only assignment
+// id if it is not 0, with their challenges.
+func loadAssignments(classroomID, id int) ([]models.Assignment, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, classroom_id, title, description, due_at, late_policy, late_penalty, created_at
+		FROM assignments
+		WHERE classroom_id = ? AND (? = 0 OR id = ?)
+		ORDER BY due_at, id
+	`, classroomID, id, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []models.Assignment{}
+	index := map[int]int{}
+	for rows.Next() {
+		a := models.Assignment{Challenges: []models.AssignmentChallenge{}}
+		err := rows.Scan(&a.ID, &a.ClassroomID, &a.Title, &a.Description, &a.DueAt, &a.LatePolicy,
+			&a.LatePenalty, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		index[a.ID] = len(assignments)
+		assignments = append(assignments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	challengeRows, err := db.DB.Query(`
+		SELECT ac.assignment_id, ac.challenge_id, c.title
+		FROM assignment_challenges ac
+		JOIN assignments a ON a.id = ac.assignment_id
+		JOIN challenges c ON c.id = ac.challenge_id
+		WHERE a.classroom_id = ? AND (? = 0 OR a.id = ?)
+		ORDER BY ac.assignment_id, ac.position
+	`, classroomID, id, id)
+	if err != nil {
+		return nil, err
+	}
+	defer challengeRows.Close()
+
+	for challengeRows.Next() {
+		var assignmentID int
+		var ch models.AssignmentChallenge
+		if err := challengeRows.Scan(&assignmentID, &ch.ChallengeID, &ch.Title); err != nil {
+			return nil, err
+		}
+		a := &assignments[index[assignmentID]]
+		a.Challenges = append(a.Challenges, ch)
+	}
+	return assignments, challengeRows.Err()
+}
+
+// assignmentFromRequest loads the classroom and assignment of the request,
+// writing the error response if either does not exist.
+func assignmentFromRequest(c *gin.Context) (*models.Classroom, *models.Assignment, bool) {
+	cl, ok := classroomFromRequest(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	id, err := strconv.Atoi(c.Param("assignment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignment ID"})
+		return nil, nil, false
+	}
+
+	assignments, err := loadAssignments(cl.ID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignment"})
+		return nil, nil, false
+	}
+	if len(assignments) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+		return nil, nil, false
+	}
+	return cl, &assignments[0], true
+}
+
+func GetAssignmentsHandler(c *gin.Context) {
+	cl, ok := classroomFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignments, err := loadAssignments(cl.ID, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"assignments": assignments,
+		"total":       len(assignments),
+	})
+}
+
+func GetAssignmentHandler(c *gin.Context) {
+	_, a, ok := assignmentFromRequest(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, a)
+}
+
+func CreateAssignmentHandler(c *gin.Context) {
+	cl, ok := classroomFromRequest(c)
+	if !ok {
+		return
+	}
+	if !canManageClassroom(c, cl) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the instructor can create assignments"})
+		return
+	}
+
+	req, ok := bindAssignment(c)
+	if !ok {
+		return
+	}
+
+	id, err := saveAssignment(cl.ID, 0, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create assignment"})
+		return
+	}
+
+	assignments, err := loadAssignments(cl.ID, id)
+	if err != nil || len(assignments) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, assignments[0])
+}
+
+// UpdateAssignmentHandler replaces an assignment. Results are computed
+// from submissions when asked for, so a new due date or late policy
+// applies to past submissions as well.
+func UpdateAssignmentHandler(c *gin.Context) {
+	cl, a, ok := assignmentFromRequest(c)
+	if !ok {
+		return
+	}
+	if !canManageClassroom(c, cl) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the instructor can edit assignments"})
+		return
+	}
+
+	req, ok := bindAssignment(c)
+	if !ok {
+		return
+	}
+
+	if _, err := saveAssignment(cl.ID, a.ID, req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update assignment"})
+		return
+	}
+
+	assignments, err := loadAssignments(cl.ID, a.ID)
+	if err != nil || len(assignments) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, assignments[0])
+}
+
+// bindAssignment reads and validates the assignment in the request body,
+// writing the error response if it is invalid.
+func bindAssignment(c *gin.Context) (AssignmentRequest, bool) {
+	var req AssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	req.applyDefaults()
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// saveAssignment inserts an assignment into a classroom when id is 0 and
+// otherwise replaces assignment id, challenges included.
+func saveAssignment(classroomID, id int, req AssignmentRequest) (int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Stored like CURRENT_TIMESTAMP so it compares with submission times.
+	dueAt := req.DueAt.UTC().Format(models.TimestampLayout)
+
+	if id == 0 {
+		result, err := tx.Exec(`
+			INSERT INTO assignments (classroom_id, title, description, due_at, late_policy, late_penalty)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, classroomID, req.Title, req.Description, dueAt, req.LatePolicy, req.LatePenalty)
+		if err != nil {
+			return 0, err
+		}
+		newID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		id = int(newID)
+	} else {
+		_, err := tx.Exec(`
+			UPDATE assignments SET title = ?, description = ?, due_at = ?, late_policy = ?, late_penalty = ?,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, req.Title, req.Description, dueAt, req.LatePolicy, req.LatePenalty, id)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("DELETE FROM assignment_challenges WHERE assignment_id = ?", id); err != nil {
+			return 0, err
+		}
+	}
+
+	for i, challengeID := range req.Challenges {
+		_, err := tx.Exec(`
+			INSERT INTO assignment_challenges (assignment_id, challenge_id, position) VALUES (?, ?, ?)
+		`, id, challengeID, i)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// GetAssignmentResultsHandler is the instructor's view of an assignment:
+// each enrolled student's best submission and credit per challenge.
+func GetAssignmentResultsHandler(c *gin.Context) {
+	cl, a, ok := assignmentFromRequest(c)
+	if !ok {
+		return
+	}
+	if !canManageClassroom(c, cl) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the instructor can view results"})
+		return
+	}
+
+	members, err := loadMembers(cl.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	results, err := assignmentResults(cl.ID, *a, members)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// assignmentResults picks each member's best submission to each challenge
+// of an assignment. Practice submissions count whenever they were made;
+// contest submissions and those still being graded do not.
+func assignmentResults(classroomID int, a models.Assignment, members []models.ClassroomMember) (models.AssignmentResults, error) {
+	results := models.AssignmentResults{Assignment: a, Students: []models.StudentResult{}}
+
+	students := map[int]int{}
+	challenges := map[int]int{}
+	for i, ch := range a.Challenges {
+		challenges[ch.ChallengeID] = i
+	}
+	for i, m := range members {
+		student := models.StudentResult{UserID: m.UserID, Username: m.Username}
+		for _, ch := range a.Challenges {
+			student.Challenges = append(student.Challenges, models.ChallengeResult{ChallengeID: ch.ChallengeID})
+		}
+		students[m.UserID] = i
+		results.Students = append(results.Students, student)
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT s.id, s.user_id, s.challenge_id, s.status, s.score, s.created_at
+		FROM submissions s
+		JOIN classroom_members m ON m.user_id = s.user_id AND m.classroom_id = ?
+		JOIN assignment_challenges ac ON ac.challenge_id = s.challenge_id AND ac.assignment_id = ?
+		WHERE s.contest_id IS NULL AND s.status NOT IN (?, ?)
+		ORDER BY s.created_at, s.id
+	`, classroomID, a.ID, judge.StatusPending, judge.StatusRunning)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+
+	passed := map[[2]int]bool{}
+	for rows.Next() {
+		var id, userID, challengeID, score int
+		var status string
+		var createdAt time.Time
+		if err := rows.Scan(&id, &userID, &challengeID, &status, &score, &createdAt); err != nil {
+			return results, err
+		}
+
+		credit, late := a.Credit(score, createdAt)
+		student := &results.Students[students[userID]]
+		result := &student.Challenges[challenges[challengeID]]
+		result.Attempts++
+		if result.SubmissionID == 0 || credit > result.Credit {
+			submittedAt := createdAt
+			result.SubmissionID, result.Status, result.Score = id, status, score
+			result.Credit, result.Late, result.SubmittedAt = credit, late, &submittedAt
+		}
+		if status == judge.StatusPassed && credit > 0 {
+			passed[[2]int{userID, challengeID}] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return results, err
+	}
+
+	for i := range results.Students {
+		student := &results.Students[i]
+		for _, result := range student.Challenges {
+			student.Credit += result.Credit
+			if passed[[2]int{student.UserID, result.ChallengeID}] {
+				student.Completed++
+			}
+		}
+	}
+	return results, nil
+}
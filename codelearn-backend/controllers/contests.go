@@ -0,0 +1,655 @@
+package controllers
+
+import (
+	"codelearn-backend/challenges"
+	"codelearn-backend/contest"
+	"codelearn-backend/db"
+	"codelearn-backend/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds on what contest organisers may configure.
+const (
+	maxContestChallenges = 26
+	maxContestDuration   = 30 * 24 * time.Hour
+	maxPenaltyMinutes    = 24 * 60
+	maxContestPoints     = 10000
+)
+
+// ContestRequest is the body of POST and PUT /contests. Challenges are
+// labelled A, B, C... in the order given. Omitted penalty minutes default
+// to 20 and omitted freeze minutes to 60, or to no freeze for contests of
+// an hour or less; zero disables them.
+type ContestRequest struct {
+	Title          string                    `json:"title"`
+	Description    string                    `json:"description"`
+	StartTime      time.Time                 `json:"start_time"`
+	EndTime        time.Time                 `json:"end_time"`
+	Scoring        string                    `json:"scoring"`
+	PenaltyMinutes *int                      `json:"penalty_minutes"`
+	FreezeMinutes  *int                      `json:"freeze_minutes"`
+	Challenges     []ContestChallengeRequest `json:"challenges"`
+}
+
+type ContestChallengeRequest struct {
+	ChallengeID int `json:"challenge_id"`
+	// Points is what solving the challenge is worth under points scoring,
+	// 100 by default.
+	Points int `json:"points"`
+}
+
+func (r *ContestRequest) applyDefaults() {
+	r.Title = strings.TrimSpace(r.Title)
+	if r.Scoring == "" {
+		r.Scoring = models.ContestICPC
+	}
+	if r.PenaltyMinutes == nil {
+		penalty := 20
+		r.PenaltyMinutes = &penalty
+	}
+	if r.FreezeMinutes == nil {
+		freeze := 60
+		if r.EndTime.Sub(r.StartTime) <= time.Hour {
+			freeze = 0
+		}
+		r.FreezeMinutes = &freeze
+	}
+	for i := range r.Challenges {
+		if r.Challenges[i].Points == 0 {
+			r.Challenges[i].Points = 100
+		}
+	}
+}
+
+// validate checks the request; the challenges must be visible to the
+// organiser.
+func (r *ContestRequest) validate(c *gin.Context) error {
+	if r.Title == "" || len(r.Title) > challenges.MaxTitleLength {
+		return fmt.Errorf("title must be between 1 and %d characters", challenges.MaxTitleLength)
+	}
+	if r.StartTime.IsZero() || !r.EndTime.After(r.StartTime) {
+		return errors.New("end_time must be after start_time")
+	}
+	if r.EndTime.Sub(r.StartTime) > maxContestDuration {
+		return errors.New("a contest can last at most 30 days")
+	}
+	if r.Scoring != models.ContestICPC && r.Scoring != models.ContestPoints {
+		return errors.New("scoring must be icpc or points")
+	}
+	if *r.PenaltyMinutes < 0 || *r.PenaltyMinutes > maxPenaltyMinutes {
+		return fmt.Errorf("penalty_minutes must be between 0 and %d", maxPenaltyMinutes)
+	}
+	if *r.FreezeMinutes < 0 || time.Duration(*r.FreezeMinutes)*time.Minute >= r.EndTime.Sub(r.StartTime) {
+		return errors.New("freeze_minutes must be shorter than the contest")
+	}
+
+	if len(r.Challenges) == 0 || len(r.Challenges) > maxContestChallenges {
+		return fmt.Errorf("a contest needs between 1 and %d challenges", maxContestChallenges)
+	}
+	seen := map[int]bool{}
+	for _, ch := range r.Challenges {
+		if seen[ch.ChallengeID] {
+			return fmt.Errorf("challenge %d is listed twice", ch.ChallengeID)
+		}
+		seen[ch.ChallengeID] = true
+		if ch.Points < 0 || ch.Points > maxContestPoints {
+			return fmt.Errorf("points must be between 1 and %d", maxContestPoints)
+		}
+		visible, err := challengeVisible(c, ch.ChallengeID)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			return fmt.Errorf("challenge %d not found", ch.ChallengeID)
+		}
+	}
+	return nil
+}
+
+// contestLabel is the label of the i-th challenge of a contest.
+func contestLabel(i int) string {
+	return string(rune('A' + i))
+}
+
+// loadContest reads a contest with its challenges, how many users
+// registered and whether userID is one of them, live or virtually.
+func loadContest(id, userID int) (*models.Contest, error) {
+	var ct models.Contest
+	var virtualStart sql.NullTime
+	err := db.DB.QueryRow(`
+		SELECT id, title, description, start_time, end_time, scoring, penalty_minutes, freeze_minutes,
+		       created_by, created_at,
+		       (SELECT COUNT(*) FROM contest_participants WHERE contest_id = contests.id AND virtual_start IS NULL),
+		       me.user_id IS NOT NULL, me.virtual_start
+		FROM contests
+		LEFT JOIN contest_participants me ON me.contest_id = contests.id AND me.user_id = ?
+		WHERE id = ?
+	`, userID, id).Scan(&ct.ID, &ct.Title, &ct.Description, &ct.StartTime, &ct.EndTime, &ct.Scoring,
+		&ct.PenaltyMinutes, &ct.FreezeMinutes, &ct.CreatedBy, &ct.CreatedAt, &ct.Participants, &ct.Registered,
+		&virtualStart)
+	if err != nil {
+		return nil, err
+	}
+	ct.Phase = ct.PhaseAt(time.Now())
+	if virtualStart.Valid {
+		ct.VirtualStart = &virtualStart.Time
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT cc.challenge_id, c.title, cc.points
+		FROM contest_challenges cc JOIN challenges c ON c.id = cc.challenge_id
+		WHERE cc.contest_id = ?
+		ORDER BY cc.position
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		ch := models.ContestChallenge{Label: contestLabel(len(ct.Challenges))}
+		if err := rows.Scan(&ch.ChallengeID, &ch.Title, &ch.Points); err != nil {
+			return nil, err
+		}
+		ct.Challenges = append(ct.Challenges, ch)
+	}
+
+	return &ct, rows.Err()
+}
+
+// contestFromRequest loads the contest of the request, writing the error
+// response if it does not exist.
+func contestFromRequest(c *gin.Context) (*models.Contest, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contest ID"})
+		return nil, false
+	}
+
+	userID, _ := c.Get("user_id")
+	ct, err := loadContest(id, userID.(int))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contest not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contest"})
+		return nil, false
+	}
+	return ct, true
+}
+
+// canManageContest reports whether the caller organises a contest or is an
+// admin. Managers see the challenges before the start and the scoreboard
+// through the freeze.
+func canManageContest(c *gin.Context, ct *models.Contest) bool {
+	role, _ := c.Get("role")
+	userID, _ := c.Get("user_id")
+	return role == models.RoleAdmin || ct.CreatedBy == userID
+}
+
+// contestChallenge finds a challenge of a contest by ID.
+func contestChallenge(ct *models.Contest, challengeID int) (models.ContestChallenge, bool) {
+	for _, ch := range ct.Challenges {
+		if ch.ChallengeID == challengeID {
+			return ch, true
+		}
+	}
+	return models.ContestChallenge{}, false
+}
+
+func GetContestsHandler(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	rows, err := db.DB.Query(`
+		SELECT id, title, description, start_time, end_time, scoring, penalty_minutes, freeze_minutes,
+		       created_by, created_at,
+		       (SELECT COUNT(*) FROM contest_participants WHERE contest_id = contests.id AND virtual_start IS NULL),
+		       me.user_id IS NOT NULL, me.virtual_start
+		FROM contests
+		LEFT JOIN contest_participants me ON me.contest_id = contests.id AND me.user_id = ?
+		ORDER BY start_time DESC
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contests"})
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	contests := []models.Contest{}
+	for rows.Next() {
+		var ct models.Contest
+		var virtualStart sql.NullTime
+		err := rows.Scan(&ct.ID, &ct.Title, &ct.Description, &ct.StartTime, &ct.EndTime, &ct.Scoring,
+			&ct.PenaltyMinutes, &ct.FreezeMinutes, &ct.CreatedBy, &ct.CreatedAt, &ct.Participants, &ct.Registered,
+			&virtualStart)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan contest"})
+			return
+		}
+		ct.Phase = ct.PhaseAt(now)
+		if virtualStart.Valid {
+			ct.VirtualStart = &virtualStart.Time
+		}
+		contests = append(contests, ct)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"contests": contests,
+		"total":    len(contests),
+	})
+}
+
+// GetContestHandler returns a contest. Its challenges stay secret until the
+// start, except to its managers.
+func GetContestHandler(c *gin.Context) {
+	ct, ok := contestFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if ct.Phase == models.ContestUpcoming && !canManageContest(c, ct) {
+		ct.Challenges = nil
+	}
+
+	c.JSON(http.StatusOK, ct)
+}
+
+func CreateContestHandler(c *gin.Context) {
+	var req ContestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.applyDefaults()
+	if err := req.validate(c); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	id, err := saveContest(0, userID.(int), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create contest"})
+		return
+	}
+
+	ct, err := loadContest(id, userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contest"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ct)
+}
+
+// UpdateContestHandler replaces a contest that has not started yet.
+func UpdateContestHandler(c *gin.Context) {
+	ct, ok := contestFromRequest(c)
+	if !ok {
+		return
+	}
+	if !canManageContest(c, ct) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own contests"})
+		return
+	}
+	if ct.Phase != models.ContestUpcoming {
+		c.JSON(http.StatusConflict, gin.H{"error": "Contest has already started"})
+		return
+	}
+
+	var req ContestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.applyDefaults()
+	if err := req.validate(c); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := saveContest(ct.ID, ct.CreatedBy, req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contest"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	ct, err := loadContest(ct.ID, userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contest"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ct)
+}
+
+// saveContest inserts a contest organised by createdBy when id is 0 and
+// otherwise replaces contest id, challenges included.
+func saveContest(id, createdBy int, req ContestRequest) (int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Times are stored like CURRENT_TIMESTAMP so SQL can compare them.
+	args := []interface{}{req.Title, req.Description,
+		req.StartTime.UTC().Format(models.TimestampLayout), req.EndTime.UTC().Format(models.TimestampLayout),
+		req.Scoring, *req.PenaltyMinutes, *req.FreezeMinutes}
+
+	if id == 0 {
+		result, err := tx.Exec(`
+			INSERT INTO contests (title, description, start_time, end_time, scoring, penalty_minutes, freeze_minutes, created_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, append(args, createdBy)...)
+		if err != nil {
+			return 0, err
+		}
+		newID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		id = int(newID)
+	} else {
+		_, err := tx.Exec(`
+			UPDATE contests SET title = ?, description = ?, start_time = ?, end_time = ?, scoring = ?,
+				penalty_minutes = ?, freeze_minutes = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, append(args, id)...)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("DELETE FROM contest_challenges WHERE contest_id = ?", id); err != nil {
+			return 0, err
+		}
+	}
+
+	for i, ch := range req.Challenges {
+		_, err := tx.Exec(`
+			INSERT INTO contest_challenges (contest_id, challenge_id, position, points)
+			VALUES (?, ?, ?, ?)
+		`, id, ch.ChallengeID, i, ch.Points)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// RegisterContestHandler registers the caller for a contest that has not
+// ended.
+func RegisterContestHandler(c *gin.Context) {
+	ct, ok := contestFromRequest(c)
+	if !ok {
+		return
+	}
+	if ct.Phase == models.ContestEnded {
+		c.JSON(http.StatusConflict, gin.H{"error": "Contest has ended; start a virtual run instead"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	_, err := db.DB.Exec("INSERT OR IGNORE INTO contest_participants (contest_id, user_id) VALUES (?, ?)",
+		ct.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Registered successfully"})
+}
+
+// UnregisterContestHandler withdraws the caller from a contest that has
+// not started.
+func UnregisterContestHandler(c *gin.Context) {
+	ct, ok := contestFromRequest(c)
+	if !ok {
+		return
+	}
+	if ct.Phase != models.ContestUpcoming {
+		c.JSON(http.StatusConflict, gin.H{"error": "Contest has already started"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	_, err := db.DB.Exec("DELETE FROM contest_participants WHERE contest_id = ? AND user_id = ?", ct.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unregister"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unregistered successfully"})
+}
+
+// StartVirtualContestHandler starts a virtual run of an ended contest for
+// the caller. Their clock starts now and runs for the contest's duration;
+// their results join the scoreboard as a ghost entry.
+func StartVirtualContestHandler(c *gin.Context) {
+	ct, ok := contestFromRequest(c)
+	if !ok {
+		return
+	}
+	if ct.Phase != models.ContestEnded {
+		c.JSON(http.StatusConflict, gin.H{"error": "Contest has not ended; register for it instead"})
+		return
+	}
+	if ct.VirtualStart != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already taken this contest virtually"})
+		return
+	}
+	if ct.Registered {
+		c.JSON(http.StatusConflict, gin.H{"error": "You took part in this contest"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	_, err := db.DB.Exec(`
+		INSERT INTO contest_participants (contest_id, user_id, virtual_start) VALUES (?, ?, ?)
+	`, ct.ID, userID, time.Now().UTC().Format(models.TimestampLayout))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start virtual contest"})
+		return
+	}
+
+	ct, err = loadContest(ct.ID, userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contest"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ct)
+}
+
+// contestProblem resolves the contest and challenge of a request for a
+// participant, writing the error response if the caller may not see the
+// challenge yet.
+func contestProblem(c *gin.Context) (*models.Contest, models.ContestChallenge, bool) {
+	ct, ok := contestFromRequest(c)
+	if !ok {
+		return nil, models.ContestChallenge{}, false
+	}
+
+	challengeID, err := strconv.Atoi(c.Param("challenge_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid challenge ID"})
+		return nil, models.ContestChallenge{}, false
+	}
+
+	manager := canManageContest(c, ct)
+	if !ct.Registered && !manager {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not registered for this contest"})
+		return nil, models.ContestChallenge{}, false
+	}
+	if ct.Phase == models.ContestUpcoming && !manager {
+		c.JSON(http.StatusConflict, gin.H{"error": "Contest has not started"})
+		return nil, models.ContestChallenge{}, false
+	}
+
+	ch, ok := contestChallenge(ct, challengeID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found in this contest"})
+		return nil, models.ContestChallenge{}, false
+	}
+	return ct, ch, true
+}
+
+// GetContestChallengeHandler returns a contest challenge with its samples,
+// whether or not the challenge itself is published.
+func GetContestChallengeHandler(c *gin.Context) {
+	_, ch, ok := contestProblem(c)
+	if !ok {
+		return
+	}
+
+	challenge, err := loadChallenge(ch.ChallengeID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return
+	}
+	hideTestCases(challenge)
+
+	c.JSON(http.StatusOK, gin.H{
+		"label":     ch.Label,
+		"points":    ch.Points,
+		"challenge": challenge,
+	})
+}
+
+// SubmitContestSolutionHandler queues a solution made in a running
+// contest or during the caller's virtual run. It only counts on the
+// contest's scoreboard.
+func SubmitContestSolutionHandler(c *gin.Context) {
+	ct, ch, ok := contestProblem(c)
+	if !ok {
+		return
+	}
+	if !ct.OpenAt(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Contest is not running"})
+		return
+	}
+
+	var req SubmitSolutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	problem, err := languageProblem(c.Request.Context(), ch.ChallengeID, req.Language)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return
+	}
+	if problem != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": problem})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	submission, err := queueSubmission(userID.(int), ch.ChallengeID, ct.ID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save submission"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":       "Solution queued for grading",
+		"submission_id": submission.ID,
+		"submission":    submission,
+	})
+}
+
+// GetScoreboardHandler returns the live scoreboard of a contest. During the
+// freeze at the end of a contest, results of later submissions are only
+// shown to its managers. During a virtual run the caller gets the
+// scoreboard as of the same time into the contest.
+func GetScoreboardHandler(c *gin.Context) {
+	ct, ok := contestFromRequest(c)
+	if !ok {
+		return
+	}
+
+	participants, err := loadParticipants(ct)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch participants"})
+		return
+	}
+	submissions, err := loadContestSubmissions(ct.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions"})
+		return
+	}
+
+	now := time.Now()
+	if ct.VirtualStart != nil && ct.OpenAt(now) {
+		elapsed := now.Sub(*ct.VirtualStart)
+		frozen := elapsed >= contest.FreezeCutoff(*ct) && !canManageContest(c, ct)
+		c.JSON(http.StatusOK, contest.Replay(*ct, participants, submissions, *ct.VirtualStart, elapsed, frozen))
+		return
+	}
+
+	frozen := ct.Phase == models.ContestRunning &&
+		!now.Before(ct.StartTime.Add(contest.FreezeCutoff(*ct))) &&
+		!canManageContest(c, ct)
+
+	c.JSON(http.StatusOK, contest.Build(*ct, participants, submissions, frozen))
+}
+
+func loadParticipants(ct *models.Contest) ([]models.ContestParticipant, error) {
+	rows, err := db.DB.Query(`
+		SELECT p.user_id, u.username, p.virtual_start FROM contest_participants p JOIN users u ON u.id = p.user_id
+		WHERE p.contest_id = ?
+	`, ct.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var participants []models.ContestParticipant
+	for rows.Next() {
+		p := models.ContestParticipant{Start: ct.StartTime}
+		var virtualStart sql.NullTime
+		if err := rows.Scan(&p.UserID, &p.Username, &virtualStart); err != nil {
+			return nil, err
+		}
+		if virtualStart.Valid {
+			p.Start, p.Virtual = virtualStart.Time, true
+		}
+		participants = append(participants, p)
+	}
+	return participants, rows.Err()
+}
+
+func loadContestSubmissions(contestID int) ([]models.ContestSubmission, error) {
+	rows, err := db.DB.Query(`
+		SELECT user_id, challenge_id, status, score, created_at FROM submissions
+		WHERE contest_id = ?
+		ORDER BY created_at, id
+	`, contestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var submissions []models.ContestSubmission
+	for rows.Next() {
+		var s models.ContestSubmission
+		if err := rows.Scan(&s.UserID, &s.ChallengeID, &s.Status, &s.Score, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, s)
+	}
+	return submissions, rows.Err()
+}
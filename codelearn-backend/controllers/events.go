@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/grader"
+	"codelearn-backend/judge"
+	"database/sql"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmissionEventsHandler streams grading progress for one of the caller's
+// submissions as server-sent events, ending with a verdict event.
+func SubmissionEventsHandler(c *gin.Context) {
+	submissionID := c.Param("id")
+	id, err := strconv.Atoi(submissionID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	// Subscribe before reading the status so a verdict published in between
+	// is not lost.
+	history, events, cancel := grader.Subscribe(id)
+	defer cancel()
+
+	var status string
+	var score int
+	err = db.DB.QueryRow("SELECT status, score FROM submissions WHERE id = ? AND user_id = ?",
+		id, userID).Scan(&status, &score)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submission"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	if status != judge.StatusPending && status != judge.StatusRunning {
+		c.SSEvent(grader.EventVerdict, grader.VerdictData{Status: status, Score: score})
+		return
+	}
+
+	if len(history) == 0 {
+		name := grader.EventQueued
+		if status == judge.StatusRunning {
+			name = grader.EventRunning
+		}
+		history = []grader.Event{{Name: name, Data: grader.StatusData{Status: status}}}
+	}
+	for _, e := range history {
+		c.SSEvent(e.Name, e.Data)
+	}
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(e.Name, e.Data)
+			return e.Name != grader.EventVerdict
+		}
+	})
+}
@@ -1,12 +1,18 @@
 package controllers
 
 import (
+	"codelearn-backend/challenges"
 	"codelearn-backend/db"
+	"codelearn-backend/grader"
+	"codelearn-backend/judge"
 	"codelearn-backend/models"
+	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,33 +23,89 @@ type SubmitSolutionRequest struct {
 	Language string `json:"language" binding:"required"`
 }
 
-type LeaderboardEntry struct {
-	Username     string `json:"username"`
-	TotalScore   int    `json:"total_score"`
-	Submissions  int    `json:"submissions"`
-	LastActivity string `json:"last_activity"`
-}
-
+// GetChallengesHandler lists the challenges the caller can see. q searches
+// titles and descriptions, tag (repeatable) requires every given tag and
+// solved=true or false filters on whether the caller has passed the
+// challenge. sort is one of newest, popularity, acceptance_rate, difficulty
+// or, with q, relevance (the default then); order overrides its direction.
 func GetChallengesHandler(c *gin.Context) {
 	difficulty := c.Query("difficulty")
 	language := c.Query("language")
+	q := strings.TrimSpace(c.Query("q"))
+	tags := challenges.NormalizeTags(c.QueryArray("tag"))
+	solved := c.Query("solved")
 	limit := c.DefaultQuery("limit", "10")
 	offset := c.DefaultQuery("offset", "0")
 
-	query := "SELECT id, title, description, difficulty, language, created_at, updated_at FROM challenges WHERE 1=1"
-	args := []interface{}{}
+	if len(q) > maxQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query too long"})
+		return
+	}
+	if solved != "" && solved != "true" && solved != "false" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "solved must be true or false"})
+		return
+	}
+	order, ok := orderBy(c.Query("sort"), c.Query("order"), q != "")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort or order"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	// Contest submissions stay out of the practice statistics, as they do
+	// on the leaderboard.
+	solvedExpr := "EXISTS(SELECT 1 FROM submissions s WHERE s.challenge_id = c.id AND s.user_id = ? AND s.status = ? AND s.contest_id IS NULL)"
+
+	query := `
+		SELECT c.id, c.title, c.description, c.difficulty, c.language, c.status, c.created_at, c.updated_at,
+		       COALESCE(st.popularity, 0) AS popularity, COALESCE(st.submissions, 0) AS submissions,
+		       COALESCE(st.accepted, 0) AS accepted, ` + solvedExpr + `,
+		       (SELECT COALESCE(GROUP_CONCAT(t.name), '') FROM challenge_tags ct JOIN tags t ON t.id = ct.tag_id
+		        WHERE ct.challenge_id = c.id)
+		FROM challenges c
+		LEFT JOIN (
+			SELECT challenge_id, COUNT(DISTINCT user_id) AS popularity, COUNT(*) AS submissions,
+			       SUM(status = ?) AS accepted
+			FROM submissions WHERE status NOT IN (?, ?) AND contest_id IS NULL
+			GROUP BY challenge_id
+		) st ON st.challenge_id = c.id`
+	args := []interface{}{userID, judge.StatusPassed, judge.StatusPassed, judge.StatusPending, judge.StatusRunning}
+
+	if q != "" {
+		join, joinArgs := searchJoin(q)
+		query += join
+		args = append(args, joinArgs...)
+	}
+
+	cond, condArgs := visibleChallenges(c)
+	query += " WHERE " + cond
+	args = append(args, condArgs...)
 
 	if difficulty != "" {
-		query += " AND difficulty = ?"
+		query += " AND c.difficulty = ?"
 		args = append(args, difficulty)
 	}
 
 	if language != "" {
-		query += " AND language = ?"
+		query += " AND c.language = ?"
 		args = append(args, language)
 	}
 
-	query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	for _, tag := range tags {
+		query += " AND EXISTS(SELECT 1 FROM challenge_tags ct JOIN tags t ON t.id = ct.tag_id WHERE ct.challenge_id = c.id AND t.name = ?)"
+		args = append(args, tag)
+	}
+
+	switch solved {
+	case "true":
+		query += " AND " + solvedExpr
+		args = append(args, userID, judge.StatusPassed)
+	case "false":
+		query += " AND NOT " + solvedExpr
+		args = append(args, userID, judge.StatusPassed)
+	}
+
+	query += order + " LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
 	rows, err := db.DB.Query(query, args...)
@@ -56,12 +118,25 @@ func GetChallengesHandler(c *gin.Context) {
 	var challenges []models.Challenge
 	for rows.Next() {
 		var challenge models.Challenge
+		var stats models.ChallengeStats
+		var solved bool
+		var tagList string
 		err := rows.Scan(&challenge.ID, &challenge.Title, &challenge.Description,
-			&challenge.Difficulty, &challenge.Language, &challenge.CreatedAt, &challenge.UpdatedAt)
+			&challenge.Difficulty, &challenge.Language, &challenge.Status, &challenge.CreatedAt, &challenge.UpdatedAt,
+			&stats.Popularity, &stats.Submissions, &stats.Accepted, &solved, &tagList)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan challenge"})
 			return
 		}
+		if stats.Submissions > 0 {
+			stats.AcceptanceRate = float64(stats.Accepted) / float64(stats.Submissions)
+		}
+		if tagList != "" {
+			challenge.Tags = strings.Split(tagList, ",")
+			sort.Strings(challenge.Tags)
+		}
+		challenge.Stats = &stats
+		challenge.Solved = &solved
 		challenges = append(challenges, challenge)
 	}
 
@@ -79,26 +154,43 @@ func GetChallengeHandler(c *gin.Context) {
 		return
 	}
 
-	var challenge models.Challenge
-	err = db.DB.QueryRow(`
-		SELECT id, title, description, difficulty, language, test_cases, created_at, updated_at
-		FROM challenges WHERE id = ?
-	`, id).Scan(&challenge.ID, &challenge.Title, &challenge.Description,
-		&challenge.Difficulty, &challenge.Language, &challenge.TestCases,
-		&challenge.CreatedAt, &challenge.UpdatedAt)
-
-	if err == sql.ErrNoRows {
+	visible, err := challengeVisible(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return
+	}
+	if !visible {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
 		return
 	}
+
+	challenge, err := loadChallenge(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
 		return
 	}
 
+	// Authors need the hidden test cases to edit their challenges; everyone
+	// else only sees the samples.
+	if !canEditChallenge(c, challenge.AuthorID) {
+		hideTestCases(challenge)
+	}
+
 	c.JSON(http.StatusOK, challenge)
 }
 
+// hideTestCases drops the hidden test cases of a challenge, leaving the
+// samples.
+func hideTestCases(challenge *models.Challenge) {
+	samples := []models.TestCase{}
+	for _, tc := range challenge.TestCases {
+		if !tc.Hidden {
+			samples = append(samples, tc)
+		}
+	}
+	challenge.TestCases = samples
+}
+
 func SubmitSolutionHandler(c *gin.Context) {
 	challengeID := c.Param("id")
 	id, err := strconv.Atoi(challengeID)
@@ -119,43 +211,90 @@ func SubmitSolutionHandler(c *gin.Context) {
 		return
 	}
 
-	var challengeExists bool
-	err = db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM challenges WHERE id = ?)", id).Scan(&challengeExists)
-	if err != nil || !challengeExists {
+	visible, err := challengeVisible(c, id)
+	if err != nil || !visible {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
 		return
 	}
 
-	status, score, output := processSubmission(req.Code, req.Language, id)
-
-	result, err := db.DB.Exec(`
-		INSERT INTO submissions (user_id, challenge_id, code, language, status, score, output)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, userID, id, req.Code, req.Language, status, score, output)
+	problem, err := languageProblem(c.Request.Context(), id, req.Language)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return
+	}
+	if problem != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": problem})
+		return
+	}
 
+	submission, err := queueSubmission(userID.(int), id, 0, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save submission"})
 		return
 	}
 
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":       "Solution queued for grading",
+		"submission_id": submission.ID,
+		"submission":    submission,
+	})
+}
+
+// languageProblem explains why code in lang cannot be graded for a
+// challenge, or returns "" if it can: the language's toolchain must be
+// installed and a function-signature challenge needs a harness for it.
+func languageProblem(ctx context.Context, challengeID int, lang string) (string, error) {
+	if _, err := judge.LookupAvailable(ctx, lang); errors.Is(err, judge.ErrLanguageUnavailable) {
+		return "Language not available on this server: " + lang, nil
+	} else if err != nil {
+		return "Unsupported language: " + lang, nil
+	}
+
+	var signature string
+	var custom int
+	err := db.DB.QueryRow(`
+		SELECT signature,
+		       (SELECT COUNT(*) FROM harnesses h WHERE h.challenge_id = c.id AND h.language = ? AND h.template != '')
+		FROM challenges c WHERE c.id = ?
+	`, lang, challengeID).Scan(&signature, &custom)
+	if err != nil {
+		return "", err
+	}
+	if signature != "" && custom == 0 && !judge.HasHarness(lang) {
+		return "Function-signature challenge has no harness for " + lang, nil
+	}
+	return "", nil
+}
+
+// queueSubmission stores a pending submission, made in contestID unless it
+// is 0, and hands it to the grader.
+func queueSubmission(userID, challengeID, contestID int, req SubmitSolutionRequest) (*models.Submission, error) {
+	var contest interface{}
+	if contestID != 0 {
+		contest = contestID
+	}
+
+	result, err := db.DB.Exec(`
+		INSERT INTO submissions (user_id, challenge_id, code, language, status, output, contest_id)
+		VALUES (?, ?, ?, ?, ?, '', ?)
+	`, userID, challengeID, req.Code, req.Language, judge.StatusPending, contest)
+	if err != nil {
+		return nil, err
+	}
+
 	submissionID, _ := result.LastInsertId()
+	grader.Enqueue(int(submissionID))
 
-	submission := models.Submission{
+	return &models.Submission{
 		ID:          int(submissionID),
-		UserID:      userID.(int),
-		ChallengeID: id,
+		UserID:      userID,
+		ChallengeID: challengeID,
 		Code:        req.Code,
 		Language:    req.Language,
-		Status:      status,
-		Score:       score,
-		Output:      output,
+		Status:      judge.StatusPending,
+		ContestID:   contestID,
 		CreatedAt:   time.Now(),
-	}
-
-	c.JSON(http.StatusCreated, gin.H{
-		"message":    "Solution submitted successfully",
-		"submission": submission,
-	})
+	}, nil
 }
 
 func GetSubmissionsHandler(c *gin.Context) {
@@ -170,7 +309,7 @@ func GetSubmissionsHandler(c *gin.Context) {
 
 	rows, err := db.DB.Query(`
 		SELECT s.id, s.user_id, s.challenge_id, s.code, s.language, s.status, s.score, s.output, s.created_at,
-		       c.title as challenge_title
+		       s.challenge_revision, c.title as challenge_title
 		FROM submissions s
 		JOIN challenges c ON s.challenge_id = c.id
 		WHERE s.user_id = ?
@@ -190,23 +329,24 @@ func GetSubmissionsHandler(c *gin.Context) {
 		var challengeTitle string
 		err := rows.Scan(&submission.ID, &submission.UserID, &submission.ChallengeID,
 			&submission.Code, &submission.Language, &submission.Status, &submission.Score,
-			&submission.Output, &submission.CreatedAt, &challengeTitle)
+			&submission.Output, &submission.CreatedAt, &submission.ChallengeRevision, &challengeTitle)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan submission"})
 			return
 		}
 
 		submissionData := map[string]interface{}{
-			"id":              submission.ID,
-			"user_id":         submission.UserID,
-			"challenge_id":    submission.ChallengeID,
-			"challenge_title": challengeTitle,
-			"code":            submission.Code,
-			"language":        submission.Language,
-			"status":          submission.Status,
-			"score":           submission.Score,
-			"output":          submission.Output,
-			"created_at":      submission.CreatedAt,
+			"id":                 submission.ID,
+			"user_id":            submission.UserID,
+			"challenge_id":       submission.ChallengeID,
+			"challenge_title":    challengeTitle,
+			"code":               submission.Code,
+			"language":           submission.Language,
+			"status":             submission.Status,
+			"score":              submission.Score,
+			"output":             submission.Output,
+			"challenge_revision": submission.ChallengeRevision,
+			"created_at":         submission.CreatedAt,
 		}
 		submissions = append(submissions, submissionData)
 	}
@@ -233,11 +373,12 @@ func GetSubmissionHandler(c *gin.Context) {
 
 	var submission models.Submission
 	err = db.DB.QueryRow(`
-		SELECT id, user_id, challenge_id, code, language, status, score, output, created_at
+		SELECT id, user_id, challenge_id, code, language, status, score, output, created_at, challenge_revision,
+		       COALESCE(contest_id, 0)
 		FROM submissions WHERE id = ? AND user_id = ?
 	`, id, userID).Scan(&submission.ID, &submission.UserID, &submission.ChallengeID,
 		&submission.Code, &submission.Language, &submission.Status, &submission.Score,
-		&submission.Output, &submission.CreatedAt)
+		&submission.Output, &submission.CreatedAt, &submission.ChallengeRevision, &submission.ContestID)
 
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
@@ -248,73 +389,49 @@ func GetSubmissionHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, submission)
-}
-
-func GetLeaderboardHandler(c *gin.Context) {
-	limit := c.DefaultQuery("limit", "10")
-
 	rows, err := db.DB.Query(`
-		SELECT u.username, 
-		       COALESCE(SUM(s.score), 0) as total_score,
-		       COUNT(s.id) as submissions,
-		       COALESCE(MAX(s.created_at), u.created_at) as last_activity
-		FROM users u
-		LEFT JOIN submissions s ON u.id = s.user_id
-		GROUP BY u.id, u.username, u.created_at
-		ORDER BY total_score DESC, submissions DESC
-		LIMIT ?
-	`, limit)
-
+		SELECT test_index, verdict, time_ms, memory_kb, hidden, input, expected, output, stderr
+		FROM submission_results WHERE submission_id = ?
+		ORDER BY test_index
+	`, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submission results"})
 		return
 	}
 	defer rows.Close()
 
-	var leaderboard []LeaderboardEntry
 	for rows.Next() {
-		var entry LeaderboardEntry
-		var lastActivity time.Time
-		err := rows.Scan(&entry.Username, &entry.TotalScore, &entry.Submissions, &lastActivity)
+		var result models.SubmissionResult
+		err := rows.Scan(&result.Index, &result.Verdict, &result.TimeMS, &result.MemoryKB, &result.Hidden,
+			&result.Input, &result.Expected, &result.Output, &result.Stderr)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan leaderboard entry"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan submission result"})
 			return
 		}
-		entry.LastActivity = lastActivity.Format("2006-01-02 15:04:05")
-		leaderboard = append(leaderboard, entry)
+		result.Redact()
+		submission.Results = append(submission.Results, result)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"leaderboard": leaderboard,
-		"total":       len(leaderboard),
-	})
-}
-
-func processSubmission(code, language string, challengeID int) (status string, score int, output string) {
-	var testCasesJSON string
-	err := db.DB.QueryRow("SELECT test_cases FROM challenges WHERE id = ?", challengeID).Scan(&testCasesJSON)
+	groupRows, err := db.DB.Query(`
+		SELECT group_name, passed, total, points, max_points
+		FROM submission_groups WHERE submission_id = ?
+		ORDER BY id
+	`, id)
 	if err != nil {
-		return "failed", 0, "Error: Could not load test cases"
-	}
-
-	var testCases []map[string]string
-	if err := json.Unmarshal([]byte(testCasesJSON), &testCases); err != nil {
-		return "failed", 0, "Error: Invalid test cases format"
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submission groups"})
+		return
 	}
+	defer groupRows.Close()
 
-	passedTests := len(testCases)
-	totalTests := len(testCases)
-
-	if passedTests == totalTests {
-		status = "passed"
-		score = 100
-		output = "All tests passed! Great job!"
-	} else {
-		status = "failed"
-		score = (passedTests * 100) / totalTests
-		output = "Some tests failed. Keep trying!"
+	for groupRows.Next() {
+		var group models.SubmissionGroup
+		err := groupRows.Scan(&group.Group, &group.Passed, &group.Total, &group.Points, &group.MaxPoints)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan submission group"})
+			return
+		}
+		submission.Groups = append(submission.Groups, group)
 	}
 
-	return status, score, output
+	c.JSON(http.StatusOK, submission)
 }
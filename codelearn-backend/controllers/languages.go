@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"codelearn-backend/judge"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetLanguagesHandler(c *gin.Context) {
+	languages := judge.Available(c.Request.Context())
+
+	c.JSON(http.StatusOK, gin.H{
+		"languages": languages,
+		"total":     len(languages),
+	})
+}
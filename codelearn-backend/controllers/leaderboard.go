@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/judge"
+	"codelearn-backend/models"
+	"codelearn-backend/stats"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxLeaderboardSize = 100
+
+// leaderboardFilter restricts which submissions count towards a
+// leaderboard.
+type leaderboardFilter struct {
+	ChallengeID int
+	Language    string
+	Since       time.Time
+	Until       time.Time
+}
+
+// parseLeaderboardFilter reads the challenge_id, language, since, until and
+// period query parameters. period is week or month, the last 7 or 30 days.
+func parseLeaderboardFilter(c *gin.Context) (leaderboardFilter, error) {
+	var f leaderboardFilter
+	var err error
+
+	if id := c.Query("challenge_id"); id != "" {
+		if f.ChallengeID, err = strconv.Atoi(id); err != nil {
+			return f, errInvalidParam("challenge_id")
+		}
+	}
+	f.Language = c.Query("language")
+
+	switch c.Query("period") {
+	case "":
+	case "week":
+		f.Since = time.Now().AddDate(0, 0, -7)
+	case "month":
+		f.Since = time.Now().AddDate(0, 0, -30)
+	default:
+		return f, errInvalidParam("period")
+	}
+	if since := c.Query("since"); since != "" {
+		if f.Since, err = parseTime(since); err != nil {
+			return f, errInvalidParam("since")
+		}
+	}
+	if until := c.Query("until"); until != "" {
+		if f.Until, err = parseTime(until); err != nil {
+			return f, errInvalidParam("until")
+		}
+	}
+
+	return f, nil
+}
+
+type errInvalidParam string
+
+func (e errInvalidParam) Error() string { return "Invalid " + string(e) }
+
+// parseTime accepts RFC 3339 timestamps and plain dates.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// condition is the SQL condition on submissions s and challenges c matching
+// the filter. Only graded submissions to published challenges count, and
+// contest submissions only count on their contest's scoreboard.
+func (f leaderboardFilter) condition() (string, []interface{}) {
+	cond := "s.status NOT IN (?, ?) AND c.status = ? AND c.deleted_at IS NULL AND s.contest_id IS NULL"
+	args := []interface{}{judge.StatusPending, judge.StatusRunning, models.ChallengePublished}
+
+	if f.ChallengeID != 0 {
+		cond += " AND s.challenge_id = ?"
+		args = append(args, f.ChallengeID)
+	}
+	if f.Language != "" {
+		cond += " AND s.language = ?"
+		args = append(args, f.Language)
+	}
+	if !f.Since.IsZero() {
+		cond += " AND s.created_at >= ?"
+		args = append(args, f.Since.UTC().Format(models.TimestampLayout))
+	}
+	if !f.Until.IsZero() {
+		cond += " AND s.created_at < ?"
+		args = append(args, f.Until.UTC().Format(models.TimestampLayout))
+	}
+	return cond, args
+}
+
+// rankedLeaderboard is the query ranking every user with a counted
+// submission. It keeps each user's best score per challenge, dated by the
+// first submission that reached it, so resubmitting a solution never
+// raises a rank.
+func rankedLeaderboard(f leaderboardFilter) (string, []interface{}) {
+	cond, args := f.condition()
+	return `
+		WITH counted AS (
+			SELECT s.user_id, s.challenge_id, s.score, s.status, s.created_at
+			FROM submissions s JOIN challenges c ON c.id = s.challenge_id
+			WHERE ` + cond + `
+		),
+		best AS (
+			SELECT user_id, challenge_id, MAX(score) AS score, MAX(status = ?) AS solved FROM counted
+			GROUP BY user_id, challenge_id
+		),
+		achieved AS (
+			SELECT b.user_id, b.score, b.solved, MIN(k.created_at) AS achieved_at
+			FROM best b JOIN counted k
+			  ON k.user_id = b.user_id AND k.challenge_id = b.challenge_id AND k.score = b.score
+			GROUP BY b.user_id, b.challenge_id
+		),
+		totals AS (
+			SELECT user_id, SUM(score) AS total_score, COUNT(*) AS challenges, SUM(solved) AS solved,
+			       MAX(achieved_at) AS achieved_at
+			FROM achieved GROUP BY user_id
+		),
+		activity AS (
+			SELECT user_id, COUNT(*) AS submissions, MAX(created_at) AS last_activity
+			FROM counted GROUP BY user_id
+		)
+		SELECT ROW_NUMBER() OVER (ORDER BY t.total_score DESC, t.achieved_at ASC, t.user_id ASC) AS rank,
+		       t.user_id, u.username, t.total_score, t.challenges, t.solved, a.submissions, t.achieved_at, a.last_activity
+		FROM totals t
+		JOIN users u ON u.id = t.user_id
+		JOIN activity a ON a.user_id = t.user_id`, append(args, judge.StatusPassed)
+}
+
+// GetLeaderboardHandler returns the top limit users and, under "me", the
+// caller's own entry, which is null if they have no counted submission.
+// The unfiltered leaderboard is served from the materialized user_stats.
+func GetLeaderboardHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxLeaderboardSize {
+		limit = maxLeaderboardSize
+	}
+
+	filter, err := parseLeaderboardFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	var leaderboard []models.LeaderboardEntry
+	var me *models.LeaderboardEntry
+	if filter == (leaderboardFilter{}) {
+		leaderboard, me, err = stats.Leaderboard(limit, userID.(int))
+	} else {
+		leaderboard, me, err = queryLeaderboard(filter, limit, userID.(int))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"leaderboard": leaderboard,
+		"total":       len(leaderboard),
+		"me":          me,
+	})
+}
+
+// queryLeaderboard computes a filtered leaderboard from the submissions.
+func queryLeaderboard(f leaderboardFilter, limit, userID int) ([]models.LeaderboardEntry, *models.LeaderboardEntry, error) {
+	query, args := rankedLeaderboard(f)
+	rows, err := db.DB.Query(`
+		SELECT * FROM (`+query+`)
+		WHERE rank <= ? OR user_id = ?
+		ORDER BY rank
+	`, append(args, limit, userID)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	leaderboard := []models.LeaderboardEntry{}
+	var me *models.LeaderboardEntry
+	for rows.Next() {
+		var entry models.LeaderboardEntry
+		err := rows.Scan(&entry.Rank, &entry.UserID, &entry.Username, &entry.TotalScore, &entry.Challenges,
+			&entry.Solved, &entry.Submissions, &entry.AchievedAt, &entry.LastActivity)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if entry.UserID == userID {
+			mine := entry
+			me = &mine
+		}
+		if entry.Rank <= limit {
+			leaderboard = append(leaderboard, entry)
+		}
+	}
+	return leaderboard, me, rows.Err()
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"bytes"
+	"codelearn-backend/challengepkg"
+	"codelearn-backend/challenges"
+	"codelearn-backend/db"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportChallengeHandler creates a challenge from a zipped package, sent
+// either as the "package" field of a multipart form or as the raw body.
+func ImportChallengeHandler(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, challengepkg.MaxTotalSize)
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		data, err = readFormFile(c, "package")
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read package: " + err.Error()})
+		return
+	}
+
+	pkg, err := challengepkg.ReadZip(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	id, err := challenges.Import(pkg, userID.(int))
+	if errors.Is(err, challenges.ErrInvalidPackage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import challenge"})
+		return
+	}
+
+	challenge, err := loadChallenge(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, challenge)
+}
+
+func readFormFile(c *gin.Context, field string) ([]byte, error) {
+	header, err := c.FormFile(field)
+	if err != nil {
+		return nil, err
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// ExportChallengeHandler downloads the current revision of a challenge as
+// a zipped package, hidden test cases included.
+func ExportChallengeHandler(c *gin.Context) {
+	id, ok := editableChallenge(c)
+	if !ok {
+		return
+	}
+
+	var revision int
+	var status string
+	err := db.DB.QueryRow("SELECT revision, status FROM challenges WHERE id = ?", id).Scan(&revision, &status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return
+	}
+
+	rev, err := loadRevision(id, revision)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revision"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := challengepkg.WriteZip(&buf, *rev.Snapshot, status); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export challenge"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="challenge-%d.zip"`, id))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
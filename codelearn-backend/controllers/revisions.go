@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/models"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldChange is one field that differs between two revisions. Diff is a
+// line diff for multi-line text fields.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+	Diff  []string    `json:"diff,omitempty"`
+}
+
+// TestCaseChange is a test case that was added, removed or changed between
+// two revisions, matched by position.
+type TestCaseChange struct {
+	Position int              `json:"position"`
+	Change   string           `json:"change"` // added, removed, changed
+	From     *models.TestCase `json:"from,omitempty"`
+	To       *models.TestCase `json:"to,omitempty"`
+}
+
+func loadRevision(challengeID, revision int) (*models.ChallengeRevision, error) {
+	rev := models.ChallengeRevision{Snapshot: &models.ChallengeSnapshot{}}
+	var data string
+	err := db.DB.QueryRow(`
+		SELECT id, challenge_id, revision, COALESCE(editor_id, 0), snapshot, created_at
+		FROM challenge_revisions WHERE challenge_id = ? AND revision = ?
+	`, challengeID, revision).Scan(&rev.ID, &rev.ChallengeID, &rev.Revision, &rev.EditorID, &data, &rev.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(data), rev.Snapshot); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// GetRevisionsHandler lists the revisions of a challenge, newest first.
+// Snapshots include hidden test cases, so only the challenge's editors can
+// see them.
+func GetRevisionsHandler(c *gin.Context) {
+	id, ok := editableChallenge(c)
+	if !ok {
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT id, challenge_id, revision, COALESCE(editor_id, 0), created_at
+		FROM challenge_revisions WHERE challenge_id = ?
+		ORDER BY revision DESC
+	`, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+	defer rows.Close()
+
+	revisions := []models.ChallengeRevision{}
+	for rows.Next() {
+		var rev models.ChallengeRevision
+		if err := rows.Scan(&rev.ID, &rev.ChallengeID, &rev.Revision, &rev.EditorID, &rev.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan revision"})
+			return
+		}
+		revisions = append(revisions, rev)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+		"total":     len(revisions),
+	})
+}
+
+func GetRevisionHandler(c *gin.Context) {
+	id, ok := editableChallenge(c)
+	if !ok {
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+
+	rev, err := loadRevision(id, revision)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revision"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+// DiffRevisionsHandler compares the revisions given by the from and to
+// query parameters field by field and test case by test case.
+func DiffRevisionsHandler(c *gin.Context) {
+	id, ok := editableChallenge(c)
+	if !ok {
+		return
+	}
+
+	fromRev, err1 := strconv.Atoi(c.Query("from"))
+	toRev, err2 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be revision numbers"})
+		return
+	}
+
+	from, err := loadRevision(id, fromRev)
+	var to *models.ChallengeRevision
+	if err == nil {
+		to, err = loadRevision(id, toRev)
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revision"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":       fromRev,
+		"to":         toRev,
+		"changes":    diffSnapshots(*from.Snapshot, *to.Snapshot),
+		"test_cases": diffTestCases(from.Snapshot.TestCases, to.Snapshot.TestCases),
+	})
+}
+
+// diffSnapshots compares every field of two snapshots except the test
+// cases, naming fields by their JSON keys.
+func diffSnapshots(from, to models.ChallengeSnapshot) []FieldChange {
+	changes := []FieldChange{}
+
+	fv, tv := reflect.ValueOf(from), reflect.ValueOf(to)
+	t := fv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "test_cases" {
+			continue
+		}
+
+		a, b := fv.Field(i).Interface(), tv.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+
+		change := FieldChange{Field: name, From: a, To: b}
+		if as, ok := a.(string); ok && (strings.Contains(as, "\n") || strings.Contains(b.(string), "\n")) {
+			change.Diff = lineDiff(as, b.(string))
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+func diffTestCases(from, to []models.TestCase) []TestCaseChange {
+	changes := []TestCaseChange{}
+	for i := 0; i < len(from) || i < len(to); i++ {
+		switch {
+		case i >= len(from):
+			changes = append(changes, TestCaseChange{Position: i, Change: "added", To: &to[i]})
+		case i >= len(to):
+			changes = append(changes, TestCaseChange{Position: i, Change: "removed", From: &from[i]})
+		case !reflect.DeepEqual(from[i], to[i]):
+			changes = append(changes, TestCaseChange{Position: i, Change: "changed", From: &from[i], To: &to[i]})
+		}
+	}
+	return changes
+}
+
+// maxDiffEdits bounds the work and memory of lineDiff, which grow with the
+// number of changed lines. Past it the changed block is reported as
+// removed and added in full rather than minimally.
+const maxDiffEdits = 1000
+
+// lineDiff returns a line diff of a and b, each line prefixed with "  ",
+// "- " or "+ ". It is minimal unless more than maxDiffEdits lines changed.
+func lineDiff(a, b string) []string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var diff []string
+	for _, line := range x[:prefix] {
+		diff = append(diff, "  "+line)
+	}
+	diff = append(diff, editScript(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, line := range x[len(x)-suffix:] {
+		diff = append(diff, "  "+line)
+	}
+	return diff
+}
+
+// editScript is Myers' O(ND) diff of x and y. v[k] is the furthest x
+// index reached on diagonal k = i-j; trace keeps v after every round to
+// walk the path back.
+func editScript(x, y []string) []string {
+	n, m := len(x), len(y)
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return replaceLines(x, y)
+		}
+		if d > 0 {
+			trace = append(trace, append([]int(nil), v[offset-d+1:offset+d]...))
+		}
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				i = v[offset+k+1]
+			} else {
+				i = v[offset+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			v[offset+k] = i
+			if i >= n && j >= m {
+				return backtrack(trace, x, y)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack rebuilds the edit script from the frontiers of editScript;
+// trace[d-1] holds diagonals -(d-1) to d-1 after round d-1.
+func backtrack(trace [][]int, x, y []string) []string {
+	var diff []string
+	i, j := len(x), len(y)
+	for d := len(trace); d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+
+		k := i - j
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevI := at(prevK)
+		prevJ := prevI - prevK
+
+		for i > prevI && j > prevJ {
+			diff = append(diff, "  "+x[i-1])
+			i--
+			j--
+		}
+		if prevK == k+1 {
+			diff = append(diff, "+ "+y[j-1])
+			j--
+		} else {
+			diff = append(diff, "- "+x[i-1])
+			i--
+		}
+	}
+	for ; i > 0; i-- {
+		diff = append(diff, "  "+x[i-1])
+	}
+
+	for l, r := 0, len(diff)-1; l < r; l, r = l+1, r-1 {
+		diff[l], diff[r] = diff[r], diff[l]
+	}
+	return diff
+}
+
+func replaceLines(x, y []string) []string {
+	diff := make([]string, 0, len(x)+len(y))
+	for _, line := range x {
+		diff = append(diff, "- "+line)
+	}
+	for _, line := range y {
+		diff = append(diff, "+ "+line)
+	}
+	return diff
+}
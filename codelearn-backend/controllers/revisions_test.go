@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{"identical", "a\nb", "a\nb", []string{"  a", "  b"}},
+		{"insertion", "a\nc", "a\nb\nc", []string{"  a", "+ b", "  c"}},
+		{"deletion", "a\nb\nc", "a\nc", []string{"  a", "- b", "  c"}},
+		{"replacement", "a\nb\nc", "a\nx\nc", []string{"  a", "- b", "+ x", "  c"}},
+		{"from empty", "", "a", []string{"- ", "+ a"}},
+		{"appended line", "a", "a\n", []string{"  a", "+ "}},
+		{
+			// The example of Myers' paper, with its edit distance of 5.
+			"myers example",
+			"A\nB\nC\nA\nB\nB\nA", "C\nB\nA\nB\nA\nC",
+			[]string{"- A", "- B", "  C", "+ B", "  A", "  B", "- B", "  A", "+ C"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineDiff(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lineDiff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// sides rebuilds both inputs of a diff.
+func sides(diff []string) (string, string) {
+	var a, b []string
+	for _, line := range diff {
+		op, text := line[:2], line[2:]
+		if op != "+ " {
+			a = append(a, text)
+		}
+		if op != "- " {
+			b = append(b, text)
+		}
+	}
+	return strings.Join(a, "\n"), strings.Join(b, "\n")
+}
+
+func edits(diff []string) int {
+	n := 0
+	for _, line := range diff {
+		if !strings.HasPrefix(line, "  ") {
+			n++
+		}
+	}
+	return n
+}
+
+// minEdits is the length of a shortest edit script, from the longest
+// common subsequence.
+func minEdits(x, y []string) int {
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return len(x) + len(y) - 2*lcs[0][0]
+}
+
+func TestLineDiffIsMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(3)))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := randomLines(), randomLines()
+		diff := lineDiff(a, b)
+		if gotA, gotB := sides(diff); gotA != a || gotB != b {
+			t.Fatalf("lineDiff(%q, %q) = %q does not rebuild its inputs", a, b, diff)
+		}
+		if got, want := edits(diff), minEdits(strings.Split(a, "\n"), strings.Split(b, "\n")); got != want {
+			t.Fatalf("lineDiff(%q, %q) = %q has %d edits, want %d", a, b, diff, got, want)
+		}
+	}
+}
+
+func TestEditScriptCutoff(t *testing.T) {
+	lines := func(prefix string, n int) []string {
+		l := make([]string, n)
+		for i := range l {
+			l[i] = fmt.Sprintf("%s%d", prefix, i)
+		}
+		return l
+	}
+
+	tests := []struct {
+		name      string
+		x, y      []string
+		wantEdits int
+		replaced  bool
+	}{
+		{
+			"at the limit",
+			append(lines("x", maxDiffEdits/2), "common"), append([]string{"common"}, lines("y", maxDiffEdits/2)...),
+			maxDiffEdits, false,
+		},
+		{"over the limit", lines("x", maxDiffEdits/2+1), lines("y", maxDiffEdits/2), maxDiffEdits + 1, true},
+		{
+			"over the limit with common lines",
+			append(lines("x", maxDiffEdits), "common"), append([]string{"common"}, lines("y", 2)...),
+			maxDiffEdits + 4, true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := editScript(tt.x, tt.y)
+			if got := edits(diff); got != tt.wantEdits {
+				t.Errorf("edits = %d, want %d", got, tt.wantEdits)
+			}
+			if replaced := reflect.DeepEqual(diff, replaceLines(tt.x, tt.y)); replaced != tt.replaced {
+				t.Errorf("replaced every line = %v, want %v", replaced, tt.replaced)
+			}
+			a, b := sides(diff)
+			if a != strings.Join(tt.x, "\n") || b != strings.Join(tt.y, "\n") {
+				t.Error("diff does not rebuild its inputs")
+			}
+		})
+	}
+}
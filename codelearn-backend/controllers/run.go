@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"codelearn-backend/grader"
+	"codelearn-backend/judge"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RunCodeRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Language string `json:"language" binding:"required"`
+	// Stdin, when present, replaces the sample test cases with a single
+	// run on this input whose output is not checked.
+	Stdin *string `json:"stdin"`
+}
+
+type RunTestResult struct {
+	Index    int    `json:"index"`
+	Verdict  string `json:"verdict"`
+	Input    string `json:"input"`
+	Expected string `json:"expected,omitempty"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	TimeMS   int64  `json:"time_ms"`
+	MemoryKB int64  `json:"memory_kb"`
+}
+
+// RunCodeHandler runs code against the sample test cases of a challenge, or
+// against custom stdin, without creating a submission.
+func RunCodeHandler(c *gin.Context) {
+	challengeID := c.Param("id")
+	id, err := strconv.Atoi(challengeID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid challenge ID"})
+		return
+	}
+
+	var req RunCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	visible, err := challengeVisible(c, id)
+	if err != nil || !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
+		return
+	}
+
+	problem, err := languageProblem(c.Request.Context(), id, req.Language)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return
+	}
+	if problem != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": problem})
+		return
+	}
+
+	job, revision, err := grader.LoadJob(id, req.Language, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load challenge"})
+		return
+	}
+
+	if req.Stdin != nil {
+		job.TestCases = []judge.TestCase{{Input: *req.Stdin}}
+		job.RunOnly = true
+	} else {
+		var samples []judge.TestCase
+		for _, tc := range job.TestCases {
+			if !tc.Hidden {
+				samples = append(samples, tc)
+			}
+		}
+		if len(samples) == 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Challenge has no sample test cases; provide stdin"})
+			return
+		}
+		job.TestCases = samples
+	}
+
+	result, err := grader.Execute(c.Request.Context(), job)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run code: " + err.Error()})
+		return
+	}
+
+	tests := []RunTestResult{}
+	for _, tr := range result.Tests {
+		tc := job.TestCases[tr.Index]
+		tests = append(tests, RunTestResult{
+			Index:    tr.Index,
+			Verdict:  tr.Verdict,
+			Input:    tc.Input,
+			Expected: tc.Expected,
+			Stdout:   tr.Output,
+			Stderr:   tr.Stderr,
+			TimeMS:   tr.TimeMS,
+			MemoryKB: tr.MemoryKB,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":             result.Status,
+		"compile_output":     result.CompileOutput,
+		"challenge_revision": revision,
+		"tests":              tests,
+	})
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxQueryLength = 200
+
+// challengeSorts maps the sort parameter of GET /challenges to its ORDER BY
+// expression and default direction. Relevance is only available with q.
+var challengeSorts = map[string]struct {
+	expr string
+	desc bool
+}{
+	"newest":          {"c.created_at", true},
+	"popularity":      {"popularity", true},
+	"acceptance_rate": {"CAST(accepted AS REAL) / NULLIF(submissions, 0)", true},
+	"difficulty":      {"CASE c.difficulty WHEN 'Easy' THEN 1 WHEN 'Medium' THEN 2 WHEN 'Hard' THEN 3 END", false},
+	"relevance":       {"m.match_rank", false},
+}
+
+// orderBy builds the ORDER BY clause for the sort and order parameters.
+// Challenges without a value, e.g. never attempted ones when sorting by
+// acceptance rate, come last either way.
+func orderBy(sort, order string, search bool) (string, bool) {
+	if sort == "" {
+		sort = "newest"
+		if search {
+			sort = "relevance"
+		}
+	}
+	s, ok := challengeSorts[sort]
+	if !ok || (sort == "relevance" && !search) {
+		return "", false
+	}
+
+	desc := s.desc
+	switch order {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		return "", false
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	return " ORDER BY " + s.expr + " " + direction + " NULLS LAST, c.id DESC", true
+}
+
+// matchQuery turns free text into an FTS5 query matching challenges that
+// contain every word, each as a prefix, so user input cannot inject FTS
+// syntax.
+func matchQuery(q string) string {
+	var terms []string
+	for _, word := range strings.Fields(q) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// likeCondition is the fallback for databases without the full-text index:
+// every word must appear in the title or description.
+func likeCondition(q string) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	for _, word := range strings.Fields(q) {
+		pattern := "%" + escaper.Replace(word) + "%"
+		conds = append(conds, `(title LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// searchJoin restricts a query on challenges c to those matching q and
+// exposes their rank as m.match_rank. Without the full-text index every
+// match ranks the same.
+func searchJoin(q string) (string, []interface{}) {
+	if db.HasSearchIndex() {
+		return `
+		JOIN (SELECT rowid AS match_id, rank AS match_rank FROM challenges_fts WHERE challenges_fts MATCH ?) m
+		  ON m.match_id = c.id`, []interface{}{matchQuery(q)}
+	}
+	cond, args := likeCondition(q)
+	return `
+		JOIN (SELECT id AS match_id, 0 AS match_rank FROM challenges WHERE ` + cond + `) m
+		  ON m.match_id = c.id`, args
+}
+
+// GetTagsHandler lists the tags of the challenges the caller can see with
+// how many challenges carry each.
+func GetTagsHandler(c *gin.Context) {
+	cond, args := visibleChallenges(c)
+	rows, err := db.DB.Query(`
+		SELECT t.name, COUNT(*) FROM tags t
+		JOIN challenge_tags ct ON ct.tag_id = t.id
+		JOIN challenges ON challenges.id = ct.challenge_id
+		WHERE `+cond+`
+		GROUP BY t.id
+		ORDER BY COUNT(*) DESC, t.name
+	`, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.Challenges); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan tag"})
+			return
+		}
+		tags = append(tags, tag)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tags":  tags,
+		"total": len(tags),
+	})
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	searchIndexOnce sync.Once
+	searchIndex     bool
+)
+
+// FTS5 reports whether the linked SQLite has the FTS5 extension, which
+// go-sqlite3 only compiles in with the sqlite_fts5 build tag.
+func FTS5() bool {
+	var enabled bool
+	err := DB.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled)
+	return err == nil && enabled
+}
+
+// HasSearchIndex reports whether challenges can be searched through the
+// challenges_fts full-text index. It is checked once per process.
+func HasSearchIndex() bool {
+	searchIndexOnce.Do(func() {
+		if !FTS5() {
+			return
+		}
+		err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'challenges_fts')").
+			Scan(&searchIndex)
+		if err != nil {
+			searchIndex = false
+		}
+	})
+	return searchIndex
+}
+
+// ErrSearchIndexUnsupported means the database has the challenges_fts index
+// or its triggers but the linked SQLite lacks FTS5, so every write to
+// challenges would fail with "no such module: fts5".
+var ErrSearchIndexUnsupported = errors.New("the database has the challenges_fts search index but SQLite was built without FTS5; build with -tags sqlite_fts5")
+
+// CheckSearchIndex returns ErrSearchIndexUnsupported if the challenges_fts
+// index or its triggers exist while FTS5 is missing. Programs writing to
+// challenges call it at startup.
+func CheckSearchIndex() error {
+	if FTS5() {
+		return nil
+	}
+	var exists bool
+	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE name LIKE 'challenges\\_fts%' ESCAPE '\\')").
+		Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrSearchIndexUnsupported
+	}
+	return nil
+}
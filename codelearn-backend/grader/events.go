@@ -0,0 +1,153 @@
+package grader
+
+import (
+	"codelearn-backend/judge"
+	"sync"
+)
+
+// Event names streamed to clients in addition to judge.EventCompiling and
+// judge.EventTest.
+const (
+	EventQueued  = "queued"
+	EventRunning = "running"
+	EventVerdict = "verdict"
+)
+
+// Event is one server-sent event about a submission. Name becomes the SSE
+// event name and Data its JSON payload.
+type Event struct {
+	Name string
+	Data interface{}
+}
+
+// StatusData is the payload of EventQueued and EventRunning.
+type StatusData struct {
+	Status string `json:"status"`
+}
+
+// VerdictData is the payload of the final EventVerdict.
+type VerdictData struct {
+	Status string `json:"status"`
+	Score  int    `json:"score"`
+}
+
+// maxHistory caps the events a topic keeps for late subscribers. Older
+// ones are dropped, so a late subscriber of a long run sees its most
+// recent progress.
+const maxHistory = 64
+
+// topic holds the events published so far for a submission that is still
+// being graded, so subscribers that connect late can catch up.
+type topic struct {
+	history []Event
+	subs    map[chan Event]struct{}
+}
+
+var (
+	topicsMu sync.Mutex
+	topics   = map[int]*topic{}
+)
+
+// Subscribe returns the events already published for the submission and a
+// channel receiving the rest. The channel is closed after EventVerdict.
+// Callers must call the returned cancel function when they stop reading.
+func Subscribe(submissionID int) ([]Event, <-chan Event, func()) {
+	topicsMu.Lock()
+	defer topicsMu.Unlock()
+
+	t, ok := topics[submissionID]
+	if !ok {
+		t = &topic{subs: map[chan Event]struct{}{}}
+		topics[submissionID] = t
+	}
+
+	ch := make(chan Event, 16)
+	t.subs[ch] = struct{}{}
+	history := append([]Event(nil), t.history...)
+
+	cancel := func() {
+		topicsMu.Lock()
+		defer topicsMu.Unlock()
+		if _, ok := t.subs[ch]; ok {
+			delete(t.subs, ch)
+			close(ch)
+		}
+		if len(t.subs) == 0 && len(t.history) == 0 && topics[submissionID] == t {
+			delete(topics, submissionID)
+		}
+	}
+
+	return history, ch, cancel
+}
+
+// publish delivers e to every subscriber of the submission. Slow subscribers
+// lose intermediate events rather than stalling the worker, but always
+// receive EventVerdict, after which the topic is discarded.
+func publish(submissionID int, e Event) {
+	topicsMu.Lock()
+	defer topicsMu.Unlock()
+
+	t, ok := topics[submissionID]
+	if !ok {
+		t = &topic{subs: map[chan Event]struct{}{}}
+		topics[submissionID] = t
+	}
+
+	final := e.Name == EventVerdict
+	for ch := range t.subs {
+		if final {
+			// Only publish sends on ch, so dropping the oldest event
+			// makes room even if the subscriber reads in between.
+			select {
+			case ch <- e:
+			default:
+				select {
+				case <-ch:
+				default:
+				}
+				ch <- e
+			}
+			close(ch)
+			delete(t.subs, ch)
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+
+	if final {
+		delete(topics, submissionID)
+		return
+	}
+	if len(t.history) == maxHistory {
+		copy(t.history, t.history[1:])
+		t.history = t.history[:maxHistory-1]
+	}
+	t.history = append(t.history, e)
+}
+
+// discard drops the topic of a submission that is no longer being graded,
+// ending the streams of its subscribers. It does nothing once EventVerdict
+// was published.
+func discard(submissionID int) {
+	topicsMu.Lock()
+	defer topicsMu.Unlock()
+
+	t, ok := topics[submissionID]
+	if !ok {
+		return
+	}
+	for ch := range t.subs {
+		close(ch)
+		delete(t.subs, ch)
+	}
+	delete(topics, submissionID)
+}
+
+func publishProgress(submissionID int) func(judge.Event) {
+	return func(e judge.Event) {
+		publish(submissionID, Event{Name: e.Type, Data: e})
+	}
+}
@@ -0,0 +1,163 @@
+package grader
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/judge"
+	"codelearn-backend/models"
+	"codelearn-backend/stats"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxStoredOutput caps the stdout and stderr kept per test case in
+// submission_results.
+const maxStoredOutput = 4 << 10
+
+// outcome is everything grading produces for a submission.
+type outcome struct {
+	Status   string
+	Score    int
+	Output   string
+	Revision int
+	Results  []models.SubmissionResult
+	Groups   []models.SubmissionGroup
+}
+
+// grade runs the judge for a claimed submission and stores the verdict.
+func grade(ctx context.Context, submissionID int) error {
+	var code, language string
+	var challengeID int
+	err := db.DB.QueryRow(`
+		SELECT challenge_id, code, language FROM submissions WHERE id = ?
+	`, submissionID).Scan(&challengeID, &code, &language)
+	if err != nil {
+		return err
+	}
+
+	publish(submissionID, Event{Name: EventRunning, Data: StatusData{Status: judge.StatusRunning}})
+	out := processSubmission(ctx, code, language, challengeID, publishProgress(submissionID))
+
+	if err := save(submissionID, out); err != nil {
+		return err
+	}
+
+	publish(submissionID, Event{Name: EventVerdict, Data: VerdictData{Status: out.Status, Score: out.Score}})
+	return nil
+}
+
+// save replaces the stored per-test results and the verdict of a
+// submission in one transaction, completing any rejudge waiting for it and
+// updating the user's stats.
+func save(submissionID int, out outcome) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM submission_results WHERE submission_id = ?", submissionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM submission_groups WHERE submission_id = ?", submissionID); err != nil {
+		return err
+	}
+
+	for _, r := range out.Results {
+		_, err := tx.Exec(`
+			INSERT INTO submission_results
+				(submission_id, test_index, verdict, time_ms, memory_kb, hidden, input, expected, output, stderr)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, submissionID, r.Index, r.Verdict, r.TimeMS, r.MemoryKB, r.Hidden, r.Input, r.Expected, r.Output, r.Stderr)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, g := range out.Groups {
+		_, err := tx.Exec(`
+			INSERT INTO submission_groups (submission_id, group_name, passed, total, points, max_points)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, submissionID, g.Group, g.Passed, g.Total, g.Points, g.MaxPoints)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE submissions SET status = ?, score = ?, output = ?, challenge_revision = ?
+		WHERE id = ?
+	`, out.Status, out.Score, out.Output, out.Revision, submissionID)
+	if err != nil {
+		return err
+	}
+	if err := finishRejudge(tx, submissionID, out.Status, out.Score); err != nil {
+		return err
+	}
+	if err := stats.RefreshSubmission(tx, submissionID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	stats.Invalidate()
+	return nil
+}
+
+func processSubmission(ctx context.Context, code, language string, challengeID int, progress func(judge.Event)) outcome {
+	job, revision, err := LoadJob(challengeID, language, code)
+	if err != nil {
+		return outcome{Status: judge.StatusInternalError, Output: "Error: " + err.Error()}
+	}
+	job.Progress = progress
+	testCases := job.TestCases
+
+	result, err := judge.Run(ctx, job)
+	if err != nil {
+		return outcome{Status: judge.StatusInternalError, Output: "Error: " + err.Error(), Revision: revision}
+	}
+
+	out := outcome{Status: result.Status, Score: result.Score, Revision: revision}
+	for _, g := range result.Groups {
+		out.Groups = append(out.Groups, models.SubmissionGroup(g))
+	}
+
+	if result.Status == judge.StatusCompileError {
+		out.Output = "Compilation failed:\n" + result.CompileOutput
+		return out
+	}
+
+	var lines []string
+	for _, tr := range result.Tests {
+		tc := testCases[tr.Index]
+		out.Results = append(out.Results, models.SubmissionResult{
+			Index:    tr.Index,
+			Verdict:  tr.Verdict,
+			TimeMS:   tr.TimeMS,
+			MemoryKB: tr.MemoryKB,
+			Hidden:   tc.Hidden,
+			Input:    tc.Input,
+			Expected: tc.Expected,
+			Output:   truncate(tr.Output, maxStoredOutput),
+			Stderr:   truncate(tr.Stderr, maxStoredOutput),
+		})
+		lines = append(lines, fmt.Sprintf("Test %d: %s (%d ms, %d KB)", tr.Index+1, tr.Verdict, tr.TimeMS, tr.MemoryKB))
+	}
+
+	if out.Status == judge.StatusPassed {
+		lines = append(lines, "All tests passed! Great job!")
+	} else {
+		lines = append(lines, "Some tests failed. Keep trying!")
+	}
+	out.Output = strings.Join(lines, "\n")
+
+	return out
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "\n... (truncated)"
+}
@@ -0,0 +1,71 @@
+package grader
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/judge"
+	"codelearn-backend/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// LoadJob builds the judge job for code written in language against a
+// challenge: its limits, scoring policy, checker, signature and harness, and
+// every test case including hidden ones. It also returns the challenge
+// revision all of that belongs to; everything is read in one transaction so
+// a concurrent edit cannot mix two revisions.
+func LoadJob(challengeID int, language, code string) (judge.Job, int, error) {
+	job := judge.Job{Code: code, Language: language}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return job, 0, err
+	}
+	defer tx.Rollback()
+
+	var timeLimitMS, memoryLimitKB, outputLimitBytes, maxProcesses, revision int
+	var signatureJSON string
+	err = tx.QueryRow(`
+		SELECT time_limit_ms, memory_limit_kb, output_limit_bytes, max_processes, scoring_policy,
+		       checker, checker_tolerance, checker_language, checker_code, signature, revision
+		FROM challenges WHERE id = ?
+	`, challengeID).Scan(&timeLimitMS, &memoryLimitKB, &outputLimitBytes, &maxProcesses, &job.ScoringPolicy,
+		&job.Checker.Name, &job.Checker.Tolerance, &job.Checker.Language, &job.Checker.Code, &signatureJSON, &revision)
+	if err != nil {
+		return job, 0, fmt.Errorf("could not load challenge: %w", err)
+	}
+	job.Limits = judge.NewLimits(timeLimitMS, memoryLimitKB, outputLimitBytes, maxProcesses)
+
+	if signatureJSON != "" {
+		job.Signature = &models.Signature{}
+		if err := json.Unmarshal([]byte(signatureJSON), job.Signature); err != nil {
+			return job, 0, fmt.Errorf("invalid function signature: %w", err)
+		}
+
+		err := tx.QueryRow("SELECT template FROM harnesses WHERE challenge_id = ? AND language = ?",
+			challengeID, language).Scan(&job.HarnessTemplate)
+		if err != nil && err != sql.ErrNoRows {
+			return job, 0, fmt.Errorf("could not load harness: %w", err)
+		}
+	}
+
+	rows, err := tx.Query(`
+		SELECT input, expected, hidden, weight, group_name, time_limit_ms
+		FROM test_cases WHERE challenge_id = ?
+		ORDER BY position, id
+	`, challengeID)
+	if err != nil {
+		return job, 0, fmt.Errorf("could not load test cases: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tc judge.TestCase
+		if err := rows.Scan(&tc.Input, &tc.Expected, &tc.Hidden, &tc.Weight, &tc.Group, &tc.TimeLimitMS); err != nil {
+			return job, 0, fmt.Errorf("could not load test cases: %w", err)
+		}
+		job.TestCases = append(job.TestCases, tc)
+	}
+
+	return job, revision, rows.Err()
+}
@@ -0,0 +1,197 @@
+package grader
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/judge"
+	"codelearn-backend/stats"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the submissions table is scanned for pending
+// rows that did not fit into the in-memory queue.
+const sweepInterval = 5 * time.Second
+
+// Queue is a bounded in-process job queue in front of the submissions
+// table. The table is the source of truth: a submission stays pending until
+// a worker claims it, so jobs dropped because the queue was full or lost in
+// a restart are picked up again by the sweeper.
+type Queue struct {
+	jobs chan int
+
+	mu     sync.Mutex
+	queued map[int]bool
+}
+
+var defaultQueue *Queue
+
+// slots bounds the jobs judged at once, each in a sandbox with the full
+// memory limit, to the size of the worker pool. Queue workers and
+// interactive runs, which bypass the queue, share them.
+var slots chan struct{}
+
+// Start creates the queue, requeues submissions left pending or running by
+// a previous process and launches the worker pool.
+func Start(ctx context.Context, workers, capacity int) error {
+	q := &Queue{
+		jobs:   make(chan int, capacity),
+		queued: make(map[int]bool),
+	}
+
+	// A submission is only marked running while a worker holds it, so any
+	// running row at startup belongs to a worker that died with the process.
+	_, err := db.DB.Exec("UPDATE submissions SET status = ? WHERE status = ?",
+		judge.StatusPending, judge.StatusRunning)
+	if err != nil {
+		return err
+	}
+
+	slots = make(chan struct{}, workers)
+	for i := 0; i < workers; i++ {
+		go q.work(ctx)
+	}
+	go q.sweep(ctx)
+
+	defaultQueue = q
+	return nil
+}
+
+// Enqueue schedules a pending submission for grading. It never blocks: when
+// the queue is full the submission is left to the sweeper.
+func Enqueue(submissionID int) {
+	publish(submissionID, Event{Name: EventQueued, Data: StatusData{Status: judge.StatusPending}})
+	if defaultQueue != nil {
+		defaultQueue.push(submissionID)
+	}
+}
+
+func (q *Queue) push(submissionID int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.queued[submissionID] {
+		return true
+	}
+
+	select {
+	case q.jobs <- submissionID:
+		q.queued[submissionID] = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *Queue) done(submissionID int) {
+	q.mu.Lock()
+	delete(q.queued, submissionID)
+	q.mu.Unlock()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-q.jobs:
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			q.run(ctx, id)
+			<-slots
+			q.done(id)
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, submissionID int) {
+	// Whether or not a verdict was published, nothing more will be.
+	defer discard(submissionID)
+
+	result, err := db.DB.Exec("UPDATE submissions SET status = ? WHERE id = ? AND status = ?",
+		judge.StatusRunning, submissionID, judge.StatusPending)
+	if err != nil {
+		log.Printf("grader: failed to claim submission %d: %v", submissionID, err)
+		return
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 0 {
+		return
+	}
+
+	if err := grade(ctx, submissionID); err != nil {
+		log.Printf("grader: failed to grade submission %d: %v", submissionID, err)
+		db.DB.Exec("UPDATE submissions SET status = ?, score = 0, output = ? WHERE id = ?",
+			judge.StatusInternalError, "Error: Grading failed", submissionID)
+		finishRejudge(db.DB, submissionID, judge.StatusInternalError, 0)
+		if err := stats.RefreshSubmission(db.DB, submissionID); err != nil {
+			log.Printf("grader: failed to update stats for submission %d: %v", submissionID, err)
+		}
+		stats.Invalidate()
+		publish(submissionID, Event{Name: EventVerdict, Data: VerdictData{Status: judge.StatusInternalError}})
+	}
+}
+
+func (q *Queue) sweep(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		q.requeuePending()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) requeuePending() {
+	room := cap(q.jobs) - len(q.jobs)
+	if room <= 0 {
+		return
+	}
+
+	rows, err := db.DB.Query("SELECT id FROM submissions WHERE status = ? ORDER BY id LIMIT ?",
+		judge.StatusPending, room)
+	if err != nil {
+		log.Printf("grader: failed to scan pending submissions: %v", err)
+		return
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("grader: failed to scan pending submission: %v", err)
+			break
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if !q.push(id) {
+			return
+		}
+	}
+}
+
+// Execute runs a job synchronously outside the queue, waiting for a slot
+// shared with the queue workers first. It is meant for interactive runs
+// that are never stored.
+func Execute(ctx context.Context, job judge.Job) (*judge.Result, error) {
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return judge.Run(ctx, job)
+}
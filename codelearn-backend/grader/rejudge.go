@@ -0,0 +1,108 @@
+package grader
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/judge"
+	"database/sql"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// RejudgeChallenge sends every finished submission to a challenge back
+// through the judge and returns the ID of the rejudge recording their
+// previous verdicts. Submissions still pending or running are left alone,
+// they will be graded against the current test cases anyway.
+func RejudgeChallenge(challengeID, requestedBy int) (int, error) {
+	return rejudge(requestedBy, challengeID, nil, "challenge_id = ?", challengeID)
+}
+
+// RejudgeSubmission is RejudgeChallenge for a single submission.
+func RejudgeSubmission(submissionID, requestedBy int) (int, error) {
+	return rejudge(requestedBy, nil, submissionID, "id = ?", submissionID)
+}
+
+func rejudge(requestedBy int, challengeID, submissionID interface{}, filter string, arg int) (int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO rejudges (challenge_id, submission_id, requested_by) VALUES (?, ?, ?)",
+		challengeID, submissionID, requestedBy)
+	if err != nil {
+		return 0, err
+	}
+	rejudgeID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err = tx.Exec(`
+		INSERT INTO rejudge_items (rejudge_id, submission_id, previous_status, previous_score)
+		SELECT ?, id, status, score FROM submissions
+		WHERE `+filter+` AND status NOT IN (?, ?)
+	`, rejudgeID, arg, judge.StatusPending, judge.StatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	total, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE submissions SET status = ?
+		WHERE id IN (SELECT submission_id FROM rejudge_items WHERE rejudge_id = ?)
+	`, judge.StatusPending, rejudgeID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE rejudges SET total = ? WHERE id = ?", total, rejudgeID); err != nil {
+		return 0, err
+	}
+
+	ids, err := rejudgeSubmissions(tx, rejudgeID)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		Enqueue(id)
+	}
+	return int(rejudgeID), nil
+}
+
+func rejudgeSubmissions(tx *sql.Tx, rejudgeID int64) ([]int, error) {
+	rows, err := tx.Query("SELECT submission_id FROM rejudge_items WHERE rejudge_id = ? ORDER BY submission_id", rejudgeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// finishRejudge records the new verdict of a submission on the rejudge that
+// is waiting for it, if any.
+func finishRejudge(e execer, submissionID int, status string, score int) error {
+	_, err := e.Exec(`
+		UPDATE rejudge_items SET new_status = ?, new_score = ?, judged_at = CURRENT_TIMESTAMP
+		WHERE submission_id = ? AND new_status IS NULL
+	`, status, score, submissionID)
+	return err
+}
@@ -0,0 +1,64 @@
+// Command importer creates challenges from challenge packages, each a
+// directory or zip archive:
+//
+//	go run importer/importer.go -author alice [-status published] pkg1 pkg2.zip ...
+package main
+
+import (
+	"codelearn-backend/challengepkg"
+	"codelearn-backend/challenges"
+	"codelearn-backend/db"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	author := flag.String("author", "", "username of the author of the imported challenges")
+	status := flag.String("status", "", "status of the imported challenges, overriding their manifests")
+	flag.Parse()
+
+	if *author == "" || flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: importer -author <username> [-status draft|published] <package>...")
+		os.Exit(2)
+	}
+
+	if err := db.InitDB(); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.CheckSearchIndex(); err != nil {
+		log.Fatal(err)
+	}
+
+	var authorID int
+	if err := db.DB.QueryRow("SELECT id FROM users WHERE username = ?", *author).Scan(&authorID); err != nil {
+		log.Fatalf("Unknown author %s: %v", *author, err)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		id, err := importPackage(path, *status, authorID)
+		if err != nil {
+			log.Printf("Failed to import %s: %v", path, err)
+			failed = true
+			continue
+		}
+		log.Printf("Imported %s as challenge %d", path, id)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func importPackage(path, status string, authorID int) (int, error) {
+	pkg, err := challengepkg.Load(path)
+	if err != nil {
+		return 0, err
+	}
+	if status != "" {
+		pkg.Status = status
+	}
+	return challenges.Import(pkg, authorID)
+}
@@ -0,0 +1,295 @@
+//go:build linux
+
+package judge
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DefaultCgroup is the name of the judge's cgroup below the root of the
+// pids and memory hierarchies.
+const DefaultCgroup = "codelearn-judge"
+
+// cgroupRoot is the judge's own cgroup, under which every run gets one of
+// its own. On the unified (v2) hierarchy pids and memory are the same
+// directory; on v1 each controller has a hierarchy of its own.
+type cgroupRoot struct {
+	unified bool
+	pids    string
+	memory  string
+	runs    atomic.Int64
+}
+
+// cgroup holds the processes of one run, so that its process count and
+// memory are limited and measured apart from the server and the helper.
+type cgroup struct {
+	unified bool
+	pids    string
+	memory  string
+}
+
+// setupCgroups creates the judge's cgroup called name, preferring the
+// unified hierarchy when it has both controllers, and removes the runs a
+// previous server left behind.
+func setupCgroups(name string) (*cgroupRoot, error) {
+	mounts, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return nil, err
+	}
+	var unified, pids, memory string
+	for _, line := range strings.Split(string(mounts), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		switch fields[2] {
+		case "cgroup2":
+			if unified == "" {
+				unified = fields[1]
+			}
+		case "cgroup":
+			for _, opt := range strings.Split(fields[3], ",") {
+				switch opt {
+				case "pids":
+					pids = fields[1]
+				case "memory":
+					memory = fields[1]
+				}
+			}
+		}
+	}
+
+	root := &cgroupRoot{}
+	switch {
+	case unified != "" && hasControllers(unified, "pids", "memory"):
+		dir := filepath.Join(unified, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		// The controllers must be enabled on every level down to the runs.
+		rel, err := filepath.Rel(unified, dir)
+		if err != nil {
+			return nil, err
+		}
+		d := unified
+		for _, part := range append(strings.Split(rel, "/"), "") {
+			if err := writeCgroupFile(d, "cgroup.subtree_control", "+pids +memory"); err != nil {
+				return nil, err
+			}
+			d = filepath.Join(d, part)
+		}
+		root.unified, root.pids, root.memory = true, dir, dir
+	case pids != "" && memory != "":
+		root.pids, root.memory = filepath.Join(pids, name), filepath.Join(memory, name)
+		for _, dir := range []string{root.pids, root.memory} {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, errors.New("no cgroup hierarchy with the pids and memory controllers")
+	}
+
+	for _, dir := range root.dirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() && strings.HasPrefix(e.Name(), "run-") {
+				removeCgroup(filepath.Join(dir, e.Name()), root.unified)
+			}
+		}
+	}
+	return root, nil
+}
+
+func hasControllers(dir string, names ...string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return false
+	}
+	available := strings.Fields(string(data))
+	for _, name := range names {
+		found := false
+		for _, a := range available {
+			found = found || a == name
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *cgroupRoot) dirs() []string {
+	if r.unified {
+		return []string{r.pids}
+	}
+	return []string{r.pids, r.memory}
+}
+
+// create makes the cgroup of a run limited to limits.Processes processes
+// and limits.Memory bytes, without swap where the kernel accounts it.
+func (r *cgroupRoot) create(limits Limits) (*cgroup, error) {
+	name := fmt.Sprintf("run-%d", r.runs.Add(1))
+	cg := &cgroup{unified: r.unified, pids: filepath.Join(r.pids, name), memory: filepath.Join(r.memory, name)}
+	for _, dir := range cg.dirs() {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			cg.destroy()
+			return nil, err
+		}
+	}
+
+	if err := cg.limit(limits); err != nil {
+		cg.destroy()
+		return nil, err
+	}
+	return cg, nil
+}
+
+func (cg *cgroup) limit(limits Limits) error {
+	if err := writeCgroupFile(cg.pids, "pids.max", strconv.Itoa(limits.Processes)); err != nil {
+		return err
+	}
+	memory := strconv.FormatInt(limits.Memory, 10)
+	memoryFile, swapFile, swap := "memory.max", "memory.swap.max", "0"
+	if !cg.unified {
+		// memsw counts memory and swap together and may not be set below
+		// the memory limit, which is therefore set first.
+		memoryFile, swapFile, swap = "memory.limit_in_bytes", "memory.memsw.limit_in_bytes", memory
+	}
+	if err := writeCgroupFile(cg.memory, memoryFile, memory); err != nil {
+		return err
+	}
+	// Swap accounting may be disabled, leaving no file to write.
+	if err := writeCgroupFile(cg.memory, swapFile, swap); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (cg *cgroup) dirs() []string {
+	if cg.unified {
+		return []string{cg.pids}
+	}
+	return []string{cg.pids, cg.memory}
+}
+
+// procs are the files the helper writes its PID to in order to join the
+// cgroup.
+func (cg *cgroup) procs() []string {
+	var files []string
+	for _, dir := range cg.dirs() {
+		files = append(files, filepath.Join(dir, "cgroup.procs"))
+	}
+	return files
+}
+
+// peakKB is the most memory the run's processes used at once, or -1 when
+// the kernel does not record it.
+func (cg *cgroup) peakKB() int64 {
+	file := "memory.max_usage_in_bytes"
+	if cg.unified {
+		file = "memory.peak"
+	}
+	data, err := os.ReadFile(filepath.Join(cg.memory, file))
+	if err != nil {
+		return -1
+	}
+	peak, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return peak >> 10
+}
+
+// oomKilled reports whether the kernel killed one of the run's processes
+// for going over the memory limit.
+func (cg *cgroup) oomKilled() bool {
+	file := "memory.oom_control"
+	if cg.unified {
+		file = "memory.events"
+	}
+	f, err := os.Open(filepath.Join(cg.memory, file))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if count, ok := strings.CutPrefix(scanner.Text(), "oom_kill "); ok {
+			n, _ := strconv.Atoi(count)
+			return n > 0
+		}
+	}
+	return false
+}
+
+// destroy kills whatever the run left running, including processes that
+// left its process group, and removes the cgroup.
+func (cg *cgroup) destroy() {
+	for _, dir := range cg.dirs() {
+		removeCgroup(dir, cg.unified)
+	}
+}
+
+func removeCgroup(dir string, unified bool) {
+	if unified {
+		// cgroup.kill exists from Linux 5.14; the loop below covers older
+		// kernels.
+		writeCgroupFile(dir, "cgroup.kill", "1")
+	}
+	for i := 0; i < 100; i++ {
+		if err := os.Remove(dir); err == nil || errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+		if err != nil {
+			return
+		}
+		for _, field := range bytes.Fields(data) {
+			if pid, err := strconv.Atoi(string(field)); err == nil {
+				syscall.Kill(pid, syscall.SIGKILL)
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644)
+}
+
+// joinCgroups opens the cgroup.procs files of a run so that the helper can
+// join it once it no longer sees the cgroup filesystem.
+func joinCgroups(procs []string) (func() error, error) {
+	var files []*os.File
+	for _, path := range procs {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return func() error {
+		pid := []byte(strconv.Itoa(os.Getpid()))
+		for _, f := range files {
+			if _, err := f.Write(pid); err != nil {
+				return fmt.Errorf("join cgroup: %w", err)
+			}
+			f.Close()
+		}
+		return nil
+	}, nil
+}
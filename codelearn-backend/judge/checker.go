@@ -0,0 +1,381 @@
+package judge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Built-in checkers selectable per challenge.
+const (
+	// CheckerExact compares output line by line, ignoring trailing
+	// whitespace and a missing final newline.
+	CheckerExact = "exact"
+	// CheckerWhitespace also ignores blank lines and how much whitespace
+	// separates the words on a line.
+	CheckerWhitespace = "whitespace"
+	// CheckerTokens compares the whitespace separated tokens, ignoring line
+	// structure entirely.
+	CheckerTokens = "tokens"
+	// CheckerFloat compares tokens, treating numeric tokens as equal when
+	// they are within CheckerConfig.Tolerance, absolute or relative.
+	CheckerFloat = "float"
+	// CheckerUnordered compares the non-blank lines as a multiset.
+	CheckerUnordered = "unordered"
+	// CheckerJSON compares output and expected as JSON values.
+	CheckerJSON = "json"
+	// CheckerSpecial runs an author provided program that decides the
+	// verdict.
+	CheckerSpecial = "special"
+)
+
+// DefaultTolerance is used by CheckerFloat when no tolerance is configured.
+const DefaultTolerance = 1e-6
+
+// checkerLimits bounds a special judge run. The checker is author code, so
+// it gets more room than the submission but is still sandboxed.
+var checkerLimits = Limits{
+	Time:      5 * time.Second,
+	Memory:    512 << 20,
+	Output:    64 << 10,
+	Processes: 64,
+}
+
+// CheckerConfig selects and configures the checker of a challenge.
+type CheckerConfig struct {
+	Name      string  `json:"name"`
+	Tolerance float64 `json:"tolerance,omitempty"`
+	// Language and Code hold the special judge source for CheckerSpecial.
+	// The program is run with the paths of the input, expected output and
+	// actual output files as arguments, must exit 0 and print AC to accept
+	// or WA to reject. Anything else is a checker failure, so a crashing
+	// checker never passes or fails a submission.
+	Language string `json:"language,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+// Checker decides whether the actual output of a test case is correct. An
+// error means the checker itself failed, not the submission.
+type Checker interface {
+	Check(ctx context.Context, tc TestCase, actual string) (bool, error)
+}
+
+// CheckerFunc adapts a comparison function to Checker.
+type CheckerFunc func(expected, actual string) bool
+
+func (f CheckerFunc) Check(ctx context.Context, tc TestCase, actual string) (bool, error) {
+	return f(tc.Expected, actual), nil
+}
+
+// ValidChecker reports whether name is a known checker.
+func ValidChecker(name string) bool {
+	switch name {
+	case CheckerExact, CheckerWhitespace, CheckerTokens, CheckerFloat,
+		CheckerUnordered, CheckerJSON, CheckerSpecial:
+		return true
+	}
+	return false
+}
+
+// newChecker builds the checker described by cfg. A special judge is
+// compiled once per source and kept in a cache; the returned cleanup
+// function releases the job's hold on it.
+func newChecker(ctx context.Context, cfg CheckerConfig) (Checker, func(), error) {
+	noop := func() {}
+
+	switch cfg.Name {
+	case "", CheckerExact:
+		return CheckerFunc(exactMatch), noop, nil
+	case CheckerWhitespace:
+		return CheckerFunc(whitespaceMatch), noop, nil
+	case CheckerTokens:
+		return CheckerFunc(tokenMatch), noop, nil
+	case CheckerFloat:
+		tolerance := cfg.Tolerance
+		if tolerance <= 0 {
+			tolerance = DefaultTolerance
+		}
+		return CheckerFunc(func(expected, actual string) bool {
+			return floatMatch(expected, actual, tolerance)
+		}), noop, nil
+	case CheckerUnordered:
+		return CheckerFunc(unorderedMatch), noop, nil
+	case CheckerJSON:
+		return CheckerFunc(jsonMatch), noop, nil
+	case CheckerSpecial:
+		return newSpecialJudge(ctx, cfg)
+	}
+	return nil, noop, fmt.Errorf("unknown checker %q", cfg.Name)
+}
+
+func exactMatch(expected, actual string) bool {
+	return normalize(actual) == normalize(expected)
+}
+
+func whitespaceMatch(expected, actual string) bool {
+	return reflect.DeepEqual(fieldLines(expected), fieldLines(actual))
+}
+
+func tokenMatch(expected, actual string) bool {
+	return reflect.DeepEqual(strings.Fields(expected), strings.Fields(actual))
+}
+
+func floatMatch(expected, actual string, tolerance float64) bool {
+	want, got := strings.Fields(expected), strings.Fields(actual)
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if want[i] == got[i] {
+			continue
+		}
+		w, errW := strconv.ParseFloat(want[i], 64)
+		g, errG := strconv.ParseFloat(got[i], 64)
+		if errW != nil || errG != nil || math.IsNaN(g) {
+			return false
+		}
+		diff := math.Abs(w - g)
+		if diff > tolerance && diff > tolerance*math.Abs(w) {
+			return false
+		}
+	}
+	return true
+}
+
+func unorderedMatch(expected, actual string) bool {
+	want, got := fieldLines(expected), fieldLines(actual)
+	sort.Strings(want)
+	sort.Strings(got)
+	return reflect.DeepEqual(want, got)
+}
+
+func jsonMatch(expected, actual string) bool {
+	var want, got interface{}
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		return exactMatch(expected, actual)
+	}
+	if err := json.Unmarshal([]byte(actual), &got); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+// fieldLines returns the non-blank lines of s with runs of whitespace
+// collapsed to a single space.
+func fieldLines(s string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			lines = append(lines, strings.Join(fields, " "))
+		}
+	}
+	return lines
+}
+
+// checkerCacheSize is how many compiled special judges are kept between
+// jobs. Rejudging a challenge would otherwise compile its checker once per
+// submission.
+const checkerCacheSize = 32
+
+// compiledChecker is a special judge built in dir, shared by every job
+// whose checker has the same language and source. Jobs hold a reference
+// while they use it; unreferenced entries are evicted least recently used
+// first.
+type compiledChecker struct {
+	dir   string
+	run   []string
+	ready chan struct{}
+	err   error
+	refs  int
+	used  time.Time
+}
+
+var checkerCache = struct {
+	sync.Mutex
+	entries map[string]*compiledChecker
+}{entries: map[string]*compiledChecker{}}
+
+type specialJudge struct {
+	*compiledChecker
+}
+
+func newSpecialJudge(ctx context.Context, cfg CheckerConfig) (Checker, func(), error) {
+	runner, ok := Lookup(cfg.Language)
+	if !ok {
+		return nil, func() {}, fmt.Errorf("checker: %w: %s", ErrUnsupportedLanguage, cfg.Language)
+	}
+
+	sum := sha256.Sum256([]byte(cfg.Code))
+	key := cfg.Language + ":" + hex.EncodeToString(sum[:])
+
+	checkerCache.Lock()
+	c, cached := checkerCache.entries[key]
+	if !cached {
+		c = &compiledChecker{ready: make(chan struct{})}
+		checkerCache.entries[key] = c
+	}
+	c.refs++
+	c.used = time.Now()
+	checkerCache.Unlock()
+	release := func() { releaseChecker(c) }
+
+	if cached {
+		select {
+		case <-c.ready:
+		case <-ctx.Done():
+			release()
+			return nil, func() {}, ctx.Err()
+		}
+	} else {
+		c.err = c.build(ctx, runner, cfg.Code)
+		if c.err != nil {
+			// Failures are not cached, so the next job tries again.
+			checkerCache.Lock()
+			delete(checkerCache.entries, key)
+			checkerCache.Unlock()
+		}
+		close(c.ready)
+	}
+	if c.err != nil {
+		release()
+		return nil, func() {}, c.err
+	}
+	return specialJudge{c}, release, nil
+}
+
+// build compiles the checker into a directory of its own. Checks run as
+// other users and only read it, so it is handed back to the server's user
+// and made readable to all.
+func (c *compiledChecker) build(ctx context.Context, runner Runner, code string) error {
+	dir, err := os.MkdirTemp("", "codelearn-checker-")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, runner.FileName()), []byte(code), 0o644); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	if argv := runner.CompileCommand(); len(argv) > 0 {
+		out, ok, err := compile(ctx, dir, argv)
+		if err == nil && !ok {
+			err = fmt.Errorf("checker failed to compile: %s", out)
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+	}
+
+	uid, gid := os.Geteuid(), os.Getegid()
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := os.Lchown(path, uid, gid); err != nil || d.Type()&fs.ModeSymlink != 0 {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mode := info.Mode().Perm() | 0o444
+		if d.IsDir() {
+			mode |= 0o111
+		}
+		return os.Chmod(path, mode)
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	c.dir, c.run = dir, runner.RunCommand()
+	return nil
+}
+
+// releaseChecker drops a job's reference to c and evicts the least recently
+// used checkers no job is using while the cache is over its size.
+func releaseChecker(c *compiledChecker) {
+	checkerCache.Lock()
+	defer checkerCache.Unlock()
+	c.refs--
+
+	for len(checkerCache.entries) > checkerCacheSize {
+		var oldestKey string
+		var oldest *compiledChecker
+		for key, e := range checkerCache.entries {
+			if e.refs == 0 && (oldest == nil || e.used.Before(oldest.used)) {
+				oldestKey, oldest = key, e
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		delete(checkerCache.entries, oldestKey)
+		os.RemoveAll(oldest.dir)
+	}
+}
+
+// Check runs the checker with its compiled directory as the read-only
+// working directory and the test files in a fresh writable one.
+func (s specialJudge) Check(ctx context.Context, tc TestCase, actual string) (bool, error) {
+	data, err := os.MkdirTemp("", "codelearn-check-")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(data)
+
+	argv := append([]string(nil), s.run...)
+	for _, file := range []struct{ name, content string }{
+		{"input.txt", tc.Input},
+		{"expected.txt", tc.Expected},
+		{"output.txt", actual},
+	} {
+		path := filepath.Join(data, file.name)
+		if err := os.WriteFile(path, []byte(file.content), 0o644); err != nil {
+			return false, err
+		}
+		argv = append(argv, path)
+	}
+
+	b := box{
+		Dir:    s.dir,
+		Mounts: []mount{{Path: s.dir}, {Path: data, Writable: true}},
+		Home:   data,
+	}
+	res, err := execute(ctx, b, argv, "", checkerLimits)
+	if err != nil {
+		return false, err
+	}
+	return checkerVerdict(res)
+}
+
+// checkerVerdict reads the verdict of a special judge run: the first word
+// it printed after exiting cleanly.
+func checkerVerdict(res *execution) (bool, error) {
+	if res.Err != nil || res.TimedOut {
+		return false, fmt.Errorf("checker failed: %v: %s", res.Err, strings.TrimSpace(res.Stderr))
+	}
+
+	fields := strings.Fields(res.Stdout)
+	if len(fields) > 0 {
+		switch fields[0] {
+		case "AC":
+			return true, nil
+		case "WA":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("checker printed no verdict: %q", strings.TrimSpace(res.Stdout))
+}
@@ -0,0 +1,151 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCheckers(t *testing.T) {
+	tests := []struct {
+		name     string
+		checker  CheckerConfig
+		expected string
+		actual   string
+		want     bool
+	}{
+		{"exact", CheckerConfig{}, "1 2\n3\n", "1 2  \n3", true},
+		{"exact inner spaces", CheckerConfig{Name: CheckerExact}, "1 2", "1  2", false},
+		{"whitespace", CheckerConfig{Name: CheckerWhitespace}, "1 2\n3\n", "\n1   2\n\n 3 \n", true},
+		{"whitespace keeps lines", CheckerConfig{Name: CheckerWhitespace}, "1 2\n3", "1\n2 3", false},
+		{"tokens", CheckerConfig{Name: CheckerTokens}, "1 2\n3", "1\n2 3", true},
+		{"tokens differ", CheckerConfig{Name: CheckerTokens}, "1 2 3", "1 2", false},
+		{"float within default tolerance", CheckerConfig{Name: CheckerFloat}, "0.333333", "0.3333334", true},
+		{"float outside default tolerance", CheckerConfig{Name: CheckerFloat}, "0.333333", "0.3334", false},
+		{"float absolute tolerance", CheckerConfig{Name: CheckerFloat, Tolerance: 0.01}, "1.00 2", "1.005 2.0", true},
+		{"float relative tolerance", CheckerConfig{Name: CheckerFloat, Tolerance: 0.01}, "1000", "1009", true},
+		{"float beyond both tolerances", CheckerConfig{Name: CheckerFloat, Tolerance: 0.01}, "1000", "1011", false},
+		{"float words compare exactly", CheckerConfig{Name: CheckerFloat}, "yes 1.0", "yes 1", true},
+		{"float word mismatch", CheckerConfig{Name: CheckerFloat}, "yes", "no", false},
+		{"float nan", CheckerConfig{Name: CheckerFloat, Tolerance: 1}, "1", "NaN", false},
+		{"float token count", CheckerConfig{Name: CheckerFloat}, "1 2", "1", false},
+		{"unordered", CheckerConfig{Name: CheckerUnordered}, "a 1\nb 2\nc 3", "c 3\n\na  1\nb 2\n", true},
+		{"unordered counts duplicates", CheckerConfig{Name: CheckerUnordered}, "a\na\nb", "a\nb\nb", false},
+		{"json ignores key order and spacing", CheckerConfig{Name: CheckerJSON}, `{"a":[1,2],"b":true}`, "{ \"b\": true, \"a\": [1, 2] }\n", true},
+		{"json numbers", CheckerConfig{Name: CheckerJSON}, "[1, 2.0]", "[1.0,2]", true},
+		{"json array order", CheckerConfig{Name: CheckerJSON}, "[1,2]", "[2,1]", false},
+		{"json invalid output", CheckerConfig{Name: CheckerJSON}, "[1]", "[1", false},
+		{"json invalid expected falls back to exact", CheckerConfig{Name: CheckerJSON}, "not json", "not json\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, cleanup, err := newChecker(context.Background(), tt.checker)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanup()
+
+			got, err := checker.Check(context.Background(), TestCase{Expected: tt.expected}, tt.actual)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Check(%q, %q) = %v, want %v", tt.expected, tt.actual, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckerVerdict(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	tests := []struct {
+		name    string
+		res     execution
+		want    bool
+		wantErr bool
+	}{
+		{"accepted", execution{Stdout: "AC\n"}, true, false},
+		{"rejected", execution{Stdout: "WA wrong sum\n"}, false, false},
+		{"leading whitespace", execution{Stdout: "\n  AC"}, true, false},
+		{"lowercase is no verdict", execution{Stdout: "ac"}, false, true},
+		{"no output", execution{}, false, true},
+		{"other output", execution{Stdout: "OK"}, false, true},
+		{"crash after AC", execution{Stdout: "AC", Err: exitErr}, false, true},
+		{"timeout", execution{Stdout: "WA", TimedOut: true}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkerVerdict(&tt.res)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("checkerVerdict() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseCheckerEvicts(t *testing.T) {
+	checkerCache.Lock()
+	saved := checkerCache.entries
+	checkerCache.entries = map[string]*compiledChecker{}
+	checkerCache.Unlock()
+	defer func() {
+		checkerCache.Lock()
+		checkerCache.entries = saved
+		checkerCache.Unlock()
+	}()
+
+	// Fill the cache to its size with idle checkers, the oldest first, and
+	// keep the oldest two in use.
+	start := time.Now()
+	entries := map[string]*compiledChecker{}
+	for i := 0; i < checkerCacheSize; i++ {
+		c := &compiledChecker{dir: t.TempDir(), used: start.Add(time.Duration(i) * time.Second)}
+		if i < 2 {
+			c.refs = 1
+		}
+		key := fmt.Sprintf("c%d", i)
+		entries[key] = c
+		checkerCache.entries[key] = c
+	}
+
+	// A new checker going idle pushes the cache over its size. The oldest
+	// idle one, c2, goes; the two in use stay though they are older.
+	added := &compiledChecker{dir: t.TempDir(), used: start.Add(time.Hour), refs: 1}
+	checkerCache.entries["new"] = added
+	releaseChecker(added)
+
+	for _, key := range []string{"c0", "c1", "c3", "new"} {
+		if _, ok := checkerCache.entries[key]; !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if _, ok := checkerCache.entries["c2"]; ok {
+		t.Error("c2 was not evicted")
+	}
+	if _, err := os.Stat(entries["c2"].dir); !os.IsNotExist(err) {
+		t.Errorf("directory of c2 not removed: %v", err)
+	}
+	if len(checkerCache.entries) != checkerCacheSize {
+		t.Errorf("cache holds %d checkers, want %d", len(checkerCache.entries), checkerCacheSize)
+	}
+
+	// With every checker in use, nothing can be evicted.
+	for _, c := range checkerCache.entries {
+		c.refs++
+	}
+	busy := &compiledChecker{dir: t.TempDir(), refs: 2}
+	checkerCache.entries["busy"] = busy
+	releaseChecker(busy)
+	if len(checkerCache.entries) != checkerCacheSize+1 {
+		t.Errorf("cache holds %d checkers, want %d", len(checkerCache.entries), checkerCacheSize+1)
+	}
+}
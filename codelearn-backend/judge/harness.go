@@ -0,0 +1,184 @@
+package judge
+
+import (
+	"bytes"
+	"codelearn-backend/models"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strings"
+	"text/template"
+)
+
+var ErrNoHarness = errors.New("no harness for language")
+
+// harness wraps a student's function into a complete program. The template
+// renders the runner's main file. When solutionFile is set the student's
+// code is written there unchanged and imported by the template; otherwise
+// the template embeds it.
+type harness struct {
+	template     string
+	solutionFile string
+}
+
+// harnessData is what harness templates are executed with.
+type harnessData struct {
+	models.Signature
+	// Code is the student's code.
+	Code string
+	// Package and Body split Go code into its package clause and the rest,
+	// so a template can add imports of its own in between.
+	Package string
+	Body    string
+}
+
+var harnesses = map[string]harness{
+	"python": {
+		solutionFile: "solution.py",
+		template: `import json
+import sys
+
+from solution import {{.Function}}
+
+args = json.loads("[" + sys.stdin.read() + "]")
+print(json.dumps({{.Function}}(*args), separators=(",", ":")))
+`,
+	},
+	"javascript": {
+		solutionFile: "solution.js",
+		template: `const fs = require("fs");
+
+const source = fs.readFileSync(__dirname + "/solution.js", "utf8");
+const solution = { exports: {} };
+const fn = new Function("module", "exports", "require", source + "\nreturn {{.Function}};")(
+  solution, solution.exports, require);
+const args = JSON.parse("[" + fs.readFileSync(0, "utf8") + "]");
+console.log(JSON.stringify(fn(...args)));
+`,
+	},
+	"go": {
+		template: `{{.Package}}
+
+import (
+	harnessJSON "encoding/json"
+	harnessFmt "fmt"
+	harnessIO "io"
+	harnessOS "os"
+)
+
+{{.Body}}
+
+func main() {
+	harnessInput, _ := harnessIO.ReadAll(harnessOS.Stdin)
+	var harnessArgs []harnessJSON.RawMessage
+	if err := harnessJSON.Unmarshal([]byte("["+string(harnessInput)+"]"), &harnessArgs); err != nil {
+		panic(err)
+	}
+	if len(harnessArgs) != {{len .Params}} {
+		panic(harnessFmt.Sprintf("expected {{len .Params}} arguments, got %d", len(harnessArgs)))
+	}
+{{range $i, $p := .Params}}
+	var harnessArg{{$i}} {{goType $p.Type}}
+	if err := harnessJSON.Unmarshal(harnessArgs[{{$i}}], &harnessArg{{$i}}); err != nil {
+		panic(err)
+	}
+{{- end}}
+
+	harnessOut, err := harnessJSON.Marshal({{.Function}}({{range $i, $p := .Params}}{{if $i}}, {{end}}harnessArg{{$i}}{{end}}))
+	if err != nil {
+		panic(err)
+	}
+	harnessFmt.Println(string(harnessOut))
+}
+`,
+	},
+}
+
+// HasHarness reports whether lang has a built-in harness, without which a
+// function-signature challenge needs a custom one to accept the language.
+func HasHarness(lang string) bool {
+	_, ok := harnesses[lang]
+	return ok
+}
+
+var harnessFuncs = template.FuncMap{
+	"goType": goType,
+}
+
+// ValidType reports whether t is a signature type: int, float, string or
+// bool, followed by any number of [].
+func ValidType(t string) bool {
+	for strings.HasSuffix(t, "[]") {
+		t = strings.TrimSuffix(t, "[]")
+	}
+	switch t {
+	case "int", "float", "string", "bool":
+		return true
+	}
+	return false
+}
+
+// goType maps a signature type to the Go type the harness decodes into.
+func goType(t string) string {
+	prefix := ""
+	for strings.HasSuffix(t, "[]") {
+		prefix += "[]"
+		t = strings.TrimSuffix(t, "[]")
+	}
+	switch t {
+	case "int":
+		return prefix + "int"
+	case "float":
+		return prefix + "float64"
+	case "string":
+		return prefix + "string"
+	case "bool":
+		return prefix + "bool"
+	}
+	return prefix + "interface{}"
+}
+
+// renderHarness returns the files to write into the workspace for code
+// implementing sig in lang, keyed by file name. custom replaces the
+// language's built-in template when not empty.
+func renderHarness(lang, mainFile, code string, sig models.Signature, custom string) (map[string]string, error) {
+	h, ok := harnesses[lang]
+	if custom != "" {
+		h.template = custom
+	} else if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoHarness, lang)
+	}
+
+	tmpl, err := template.New(lang).Funcs(harnessFuncs).Parse(h.template)
+	if err != nil {
+		return nil, err
+	}
+
+	data := harnessData{Signature: sig, Code: code, Package: "package main", Body: code}
+	if lang == "go" {
+		data.Package, data.Body = splitPackageClause(code)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	files := map[string]string{mainFile: buf.String()}
+	if h.solutionFile != "" {
+		files[h.solutionFile] = code
+	}
+	return files, nil
+}
+
+// splitPackageClause separates the package clause of Go source from the
+// rest, defaulting to package main when the student omitted it.
+func splitPackageClause(code string) (string, string) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", code, parser.PackageClauseOnly)
+	if err != nil || f.Name == nil {
+		return "package main", code
+	}
+	end := int(f.Name.End()) - 1
+	return code[:end], code[end:]
+}
@@ -0,0 +1,170 @@
+package judge
+
+import (
+	"codelearn-backend/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderHarness(t *testing.T) {
+	sig := models.Signature{
+		Function: "add",
+		Params:   []models.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int[]"}},
+		Returns:  "int",
+	}
+
+	tests := []struct {
+		name     string
+		lang     string
+		mainFile string
+		code     string
+		custom   string
+		// want maps each expected file to fragments of its content.
+		want map[string][]string
+		err  error
+	}{
+		{
+			name:     "python imports the solution file",
+			lang:     "python",
+			mainFile: "main.py",
+			code:     "def add(a, b):\n    return a + sum(b)\n",
+			want: map[string][]string{
+				"main.py":     {"from solution import add", "print(json.dumps(add(*args)"},
+				"solution.py": {"def add(a, b):"},
+			},
+		},
+		{
+			name:     "javascript evaluates the solution file",
+			lang:     "javascript",
+			mainFile: "main.js",
+			code:     "function add(a, b) { return a + b.length; }",
+			want: map[string][]string{
+				"main.js":     {`"/solution.js"`, "return add;"},
+				"solution.js": {"function add(a, b)"},
+			},
+		},
+		{
+			name:     "go keeps the package clause before the imports",
+			lang:     "go",
+			mainFile: "main.go",
+			code:     "package main\n\nfunc add(a int, b []int) int { return a }\n",
+			want: map[string][]string{
+				"main.go": {
+					"package main\n\nimport (",
+					"var harnessArg0 int\n",
+					"var harnessArg1 []int\n",
+					"add(harnessArg0, harnessArg1)",
+					"expected 2 arguments",
+				},
+			},
+		},
+		{
+			name:     "go without a package clause",
+			lang:     "go",
+			mainFile: "main.go",
+			code:     "func add(a int, b []int) int { return a }\n",
+			want: map[string][]string{
+				"main.go": {"package main\n\nimport (", "func add(a int, b []int) int"},
+			},
+		},
+		{
+			name:     "custom template replaces the built-in one",
+			lang:     "python",
+			mainFile: "main.py",
+			code:     "def add(a, b): pass",
+			custom:   "# {{.Function}} takes {{len .Params}}\n",
+			want: map[string][]string{
+				"main.py":     {"# add takes 2"},
+				"solution.py": {"def add(a, b): pass"},
+			},
+		},
+		{
+			name:     "custom template embeds the code for other languages",
+			lang:     "c",
+			mainFile: "main.c",
+			code:     "int add(int a) { return a; }",
+			custom:   "{{.Code}}\nint main(void) { return {{.Function}}(0); }\n",
+			want: map[string][]string{
+				"main.c": {"int add(int a) { return a; }\nint main(void) { return add(0); }"},
+			},
+		},
+		{
+			name:     "no harness for the language",
+			lang:     "c",
+			mainFile: "main.c",
+			code:     "int add(int a) { return a; }",
+			err:      ErrNoHarness,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := renderHarness(tt.lang, tt.mainFile, tt.code, sig, tt.custom)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != len(tt.want) {
+				t.Errorf("files = %d, want %d", len(files), len(tt.want))
+			}
+			for name, fragments := range tt.want {
+				content, ok := files[name]
+				if !ok {
+					t.Errorf("%s not rendered", name)
+					continue
+				}
+				for _, fragment := range fragments {
+					if !strings.Contains(content, fragment) {
+						t.Errorf("%s does not contain %q:\n%s", name, fragment, content)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGoType(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"int", "int"},
+		{"float", "float64"},
+		{"string[]", "[]string"},
+		{"bool[][]", "[][]bool"},
+		{"map", "interface{}"},
+	}
+
+	for _, tt := range tests {
+		if got := goType(tt.in); got != tt.want {
+			t.Errorf("goType(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidType(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"int", true},
+		{"float[]", true},
+		{"string[][]", true},
+		{"bool", true},
+		{"", false},
+		{"[]", false},
+		{"int[", false},
+		{"map", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidType(tt.in); got != tt.want {
+			t.Errorf("ValidType(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
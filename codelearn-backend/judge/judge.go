@@ -0,0 +1,226 @@
+package judge
+
+import (
+	"codelearn-backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Verdicts stored in Submission.Status and reported per test case.
+const (
+	StatusPending             = "pending"
+	StatusRunning             = "running"
+	StatusPassed              = "passed"
+	StatusWrongAnswer         = "wrong_answer"
+	StatusCompileError        = "compile_error"
+	StatusRuntimeError        = "runtime_error"
+	StatusTimeLimitExceeded   = "time_limit_exceeded"
+	StatusMemoryLimitExceeded = "memory_limit_exceeded"
+	StatusOutputLimitExceeded = "output_limit_exceeded"
+	StatusInternalError       = "internal_error"
+)
+
+// compileTimeout bounds the wall and CPU time of the compile step. The
+// first Go build fills the shared build cache with the standard library,
+// which takes tens of seconds.
+const compileTimeout = 60 * time.Second
+
+type TestCase struct {
+	Input    string `json:"input"`
+	Expected string `json:"expected"`
+	Hidden   bool   `json:"hidden"`
+	// Weight is the number of points the case is worth. Zero counts as 1.
+	Weight int `json:"weight,omitempty"`
+	// Group names the subtask the case belongs to under PolicySubtask.
+	Group string `json:"group,omitempty"`
+	// TimeLimitMS overrides Job.Limits.Time for this case when non-zero.
+	TimeLimitMS int `json:"time_limit_ms,omitempty"`
+}
+
+func (tc TestCase) weight() int {
+	if tc.Weight <= 0 {
+		return 1
+	}
+	return tc.Weight
+}
+
+type TestResult struct {
+	Index    int    `json:"index"`
+	Verdict  string `json:"verdict"`
+	Output   string `json:"output"`
+	Stderr   string `json:"stderr,omitempty"`
+	TimeMS   int64  `json:"time_ms"`
+	WallMS   int64  `json:"wall_ms"`
+	MemoryKB int64  `json:"memory_kb"`
+}
+
+// Event types reported through Job.Progress.
+const (
+	EventCompiling = "compiling"
+	EventTest      = "test"
+)
+
+// Event reports grading progress. Test is 1-based and only set for
+// EventTest.
+type Event struct {
+	Type     string `json:"type"`
+	Test     int    `json:"test,omitempty"`
+	Total    int    `json:"total"`
+	Verdict  string `json:"verdict,omitempty"`
+	TimeMS   int64  `json:"time_ms,omitempty"`
+	MemoryKB int64  `json:"memory_kb,omitempty"`
+}
+
+type Job struct {
+	Code      string
+	Language  string
+	TestCases []TestCase
+	Limits    Limits
+	// ScoringPolicy is one of the Policy constants.
+	ScoringPolicy string
+	// Checker decides whether a test's output is correct. The zero value
+	// is CheckerExact.
+	Checker CheckerConfig
+	// Signature, if set, makes Code a single function that a harness calls
+	// with each test's arguments instead of a program reading stdin.
+	Signature *models.Signature
+	// HarnessTemplate replaces the built-in harness of the language.
+	HarnessTemplate string
+	// RunOnly skips the checker, for runs on custom input without an
+	// expected output. A clean exit is then reported as StatusPassed.
+	RunOnly bool
+	// Progress, if set, is called synchronously as grading advances.
+	Progress func(Event)
+}
+
+func (j Job) report(e Event) {
+	if j.Progress != nil {
+		j.Progress(e)
+	}
+}
+
+type Result struct {
+	Status        string       `json:"status"`
+	Score         int          `json:"score"`
+	Passed        int          `json:"passed"`
+	Total         int          `json:"total"`
+	CompileOutput string       `json:"compile_output,omitempty"`
+	Tests         []TestResult `json:"tests"`
+	Groups        []GroupScore `json:"groups"`
+}
+
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+// Run writes the job's code into a fresh temporary workspace, compiles it
+// with the runner registered for its language if needed and runs it once per
+// test case under the job's limits, feeding the case input on stdin and
+// handing stdout to the job's checker.
+//
+// The overall status is StatusPassed when every test passed, otherwise the
+// verdict of the first test that did not. The score follows the job's
+// scoring policy.
+func Run(ctx context.Context, job Job) (*Result, error) {
+	runner, ok := Lookup(job.Language)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, job.Language)
+	}
+
+	dir, err := os.MkdirTemp("", "codelearn-judge-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{runner.FileName(): job.Code}
+	if job.Signature != nil {
+		files, err = renderHarness(job.Language, runner.FileName(), job.Code, *job.Signature, job.HarnessTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("harness: %w", err)
+		}
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	checker, cleanup, err := newChecker(ctx, job.Checker)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	result := &Result{Status: StatusPassed, Total: len(job.TestCases)}
+
+	if argv := runner.CompileCommand(); len(argv) > 0 {
+		job.report(Event{Type: EventCompiling, Total: result.Total})
+		out, ok, err := compile(ctx, dir, argv)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			result.Status = StatusCompileError
+			result.CompileOutput = out
+			result.Score, result.Groups = Score(job.ScoringPolicy, job.TestCases, nil)
+			return result, nil
+		}
+	}
+
+	run := runner.RunCommand()
+	for i, tc := range job.TestCases {
+		limits := job.Limits
+		if tc.TimeLimitMS > 0 {
+			limits.Time = time.Duration(tc.TimeLimitMS) * time.Millisecond
+		}
+
+		res, err := execute(ctx, workspace(dir), run, tc.Input, limits)
+		if err != nil {
+			return nil, err
+		}
+
+		tr := TestResult{
+			Index:    i,
+			Verdict:  res.verdict(limits),
+			Output:   res.Stdout,
+			Stderr:   res.Stderr,
+			TimeMS:   res.CPUTime.Milliseconds(),
+			WallMS:   res.WallTime.Milliseconds(),
+			MemoryKB: res.MemoryKB,
+		}
+		if tr.Verdict == StatusPassed && !job.RunOnly {
+			ok, err := checker.Check(ctx, tc, res.Stdout)
+			switch {
+			case err != nil:
+				// The checker's own diagnostics may reveal the expected
+				// answer, so only the verdict is reported.
+				tr.Verdict = StatusInternalError
+			case !ok:
+				tr.Verdict = StatusWrongAnswer
+			}
+		}
+
+		if tr.Verdict == StatusPassed {
+			result.Passed++
+		} else if result.Status == StatusPassed {
+			result.Status = tr.Verdict
+		}
+		result.Tests = append(result.Tests, tr)
+
+		job.report(Event{
+			Type:     EventTest,
+			Test:     i + 1,
+			Total:    result.Total,
+			Verdict:  tr.Verdict,
+			TimeMS:   tr.TimeMS,
+			MemoryKB: tr.MemoryKB,
+		})
+	}
+
+	result.Score, result.Groups = Score(job.ScoringPolicy, job.TestCases, result.Tests)
+
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package judge
+
+import "time"
+
+// Limits bounds the resources a single test case run may use.
+type Limits struct {
+	// Time is the CPU time limit.
+	Time time.Duration `json:"time"`
+	// WallTime is the real time limit. Zero derives it from Time so that a
+	// program blocked on I/O or sleeping is still stopped.
+	WallTime time.Duration `json:"wall_time"`
+	// Memory is the memory limit in bytes: of the run's cgroup where the
+	// judge has one, else of each process's data segment.
+	Memory int64 `json:"memory"`
+	// Output is the maximum number of bytes a program may write to stdout.
+	// Stderr is truncated to the same size.
+	Output int64 `json:"output"`
+	// FileSize is the largest file a program may write, Output if zero.
+	FileSize int64 `json:"file_size,omitempty"`
+	// Processes is the maximum number of processes and threads, enforced
+	// by the run's cgroup. Without one the kernel counts them per user,
+	// which only works when every run has a UID of its own.
+	Processes int `json:"processes"`
+}
+
+var DefaultLimits = Limits{
+	Time:      2 * time.Second,
+	Memory:    256 << 20,
+	Output:    64 << 10,
+	Processes: 64,
+}
+
+// NewLimits builds Limits from the per-challenge columns stored in the
+// challenges table, falling back to DefaultLimits for unset values.
+func NewLimits(timeLimitMS, memoryLimitKB, outputLimitBytes, maxProcesses int) Limits {
+	limits := DefaultLimits
+	if timeLimitMS > 0 {
+		limits.Time = time.Duration(timeLimitMS) * time.Millisecond
+	}
+	if memoryLimitKB > 0 {
+		limits.Memory = int64(memoryLimitKB) << 10
+	}
+	if outputLimitBytes > 0 {
+		limits.Output = int64(outputLimitBytes)
+	}
+	if maxProcesses > 0 {
+		limits.Processes = maxProcesses
+	}
+	return limits
+}
+
+func (l Limits) fileSize() int64 {
+	if l.FileSize > 0 {
+		return l.FileSize
+	}
+	return l.Output
+}
+
+func (l Limits) wallTime() time.Duration {
+	if l.WallTime > 0 {
+		return l.WallTime
+	}
+	return 2*l.Time + time.Second
+}
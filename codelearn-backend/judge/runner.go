@@ -0,0 +1,178 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionTimeout bounds how long a version probe may take before the
+// toolchain is considered unavailable.
+const versionTimeout = 5 * time.Second
+
+// probeTTL is how long the outcome of a version probe is reused, so that
+// toolchains installed or removed while the server runs are noticed.
+const probeTTL = 5 * time.Minute
+
+// Runner describes how to build and run source code written in one
+// language. Commands are executed inside the submission workspace.
+type Runner interface {
+	// Language is the registry key, matching models.Challenge.Language and
+	// models.Submission.Language.
+	Language() string
+	// FileName is the name the source code is written to in the workspace.
+	FileName() string
+	// CompileCommand returns the build command, or nil for interpreted
+	// languages.
+	CompileCommand() []string
+	// RunCommand returns the command that executes the program.
+	RunCommand() []string
+	// Version probes the host toolchain and returns its version string. An
+	// error means the language cannot be graded on this host.
+	Version(ctx context.Context) (string, error)
+}
+
+type LanguageInfo struct {
+	Language string `json:"language"`
+	Version  string `json:"version"`
+}
+
+var ErrLanguageUnavailable = errors.New("language toolchain not installed")
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Runner{}
+)
+
+type probe struct {
+	version string
+	err     error
+	at      time.Time
+}
+
+var (
+	probesMu sync.Mutex
+	probes   = map[string]probe{}
+)
+
+// Register adds a runner to the registry, replacing any runner already
+// registered for the same language.
+func Register(r Runner) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[r.Language()] = r
+
+	probesMu.Lock()
+	delete(probes, r.Language())
+	probesMu.Unlock()
+}
+
+// Lookup returns the runner registered for lang.
+func Lookup(lang string) (Runner, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	r, ok := registry[lang]
+	return r, ok
+}
+
+// LookupAvailable returns the runner registered for lang if its toolchain
+// is installed on this host, as last probed by Available. The error wraps
+// ErrUnsupportedLanguage or ErrLanguageUnavailable.
+func LookupAvailable(ctx context.Context, lang string) (Runner, error) {
+	r, ok := Lookup(lang)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, lang)
+	}
+	if _, err := version(ctx, r); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrLanguageUnavailable, lang)
+	}
+	return r, nil
+}
+
+// version returns the toolchain version of r, probing it at most once per
+// probeTTL.
+func version(ctx context.Context, r Runner) (string, error) {
+	probesMu.Lock()
+	p, ok := probes[r.Language()]
+	probesMu.Unlock()
+	if ok && time.Since(p.at) < probeTTL {
+		return p.version, p.err
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+	v, err := r.Version(probeCtx)
+	// A probe cut short by the caller says nothing about the toolchain.
+	if ctx.Err() == nil {
+		probesMu.Lock()
+		probes[r.Language()] = probe{version: v, err: err, at: time.Now()}
+		probesMu.Unlock()
+	}
+	return v, err
+}
+
+// Available probes every registered runner and returns the languages whose
+// toolchain is installed on this host, sorted by name. Probe results are
+// cached for probeTTL.
+func Available(ctx context.Context) []LanguageInfo {
+	registryMu.RLock()
+	runners := make([]Runner, 0, len(registry))
+	for _, r := range registry {
+		runners = append(runners, r)
+	}
+	registryMu.RUnlock()
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		languages []LanguageInfo
+	)
+	for _, r := range runners {
+		wg.Add(1)
+		go func(r Runner) {
+			defer wg.Done()
+			v, err := version(ctx, r)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			languages = append(languages, LanguageInfo{Language: r.Language(), Version: v})
+			mu.Unlock()
+		}(r)
+	}
+	wg.Wait()
+
+	sort.Slice(languages, func(i, j int) bool {
+		return languages[i].Language < languages[j].Language
+	})
+	return languages
+}
+
+// CommandRunner is a Runner defined entirely by fixed command lines, which
+// covers every built-in language.
+type CommandRunner struct {
+	Name    string
+	File    string
+	Compile []string
+	Run     []string
+	Probe   []string
+}
+
+func (r CommandRunner) Language() string         { return r.Name }
+func (r CommandRunner) FileName() string         { return r.File }
+func (r CommandRunner) CompileCommand() []string { return r.Compile }
+func (r CommandRunner) RunCommand() []string     { return r.Run }
+
+func (r CommandRunner) Version(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, r.Probe[0], r.Probe[1:]...).CombinedOutput()
+	if err != nil {
+		return "", err
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return version, nil
+}
@@ -0,0 +1,51 @@
+package judge
+
+func init() {
+	Register(CommandRunner{
+		Name:  "python",
+		File:  "main.py",
+		Run:   []string{"python3", "main.py"},
+		Probe: []string{"python3", "--version"},
+	})
+	Register(CommandRunner{
+		Name:  "javascript",
+		File:  "main.js",
+		Run:   []string{"node", "main.js"},
+		Probe: []string{"node", "--version"},
+	})
+	Register(CommandRunner{
+		Name:    "go",
+		File:    "main.go",
+		Compile: []string{"go", "build", "-o", "main", "main.go"},
+		Run:     []string{"./main"},
+		Probe:   []string{"go", "version"},
+	})
+	Register(CommandRunner{
+		Name:    "c",
+		File:    "main.c",
+		Compile: []string{"gcc", "-O2", "-std=c11", "-o", "main", "main.c", "-lm"},
+		Run:     []string{"./main"},
+		Probe:   []string{"gcc", "--version"},
+	})
+	Register(CommandRunner{
+		Name:    "cpp",
+		File:    "main.cpp",
+		Compile: []string{"g++", "-O2", "-std=c++17", "-o", "main", "main.cpp"},
+		Run:     []string{"./main"},
+		Probe:   []string{"g++", "--version"},
+	})
+	Register(CommandRunner{
+		Name:    "rust",
+		File:    "main.rs",
+		Compile: []string{"rustc", "-O", "-o", "main", "main.rs"},
+		Run:     []string{"./main"},
+		Probe:   []string{"rustc", "--version"},
+	})
+	Register(CommandRunner{
+		Name:    "csharp",
+		File:    "main.cs",
+		Compile: []string{"mcs", "-out:main.exe", "main.cs"},
+		Run:     []string{"mono", "main.exe"},
+		Probe:   []string{"mono", "--version"},
+	})
+}
@@ -0,0 +1,256 @@
+package judge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotIsolated is returned by SetupSandbox when programs could not be
+// isolated from the server and SandboxConfig.AllowUnsandboxed is not set.
+var ErrNotIsolated = errors.New("sandbox: programs cannot be isolated from the server")
+
+// execution is the outcome of running one command inside the sandbox.
+type execution struct {
+	Stdout         string
+	Stderr         string
+	Err            error
+	CPUTime        time.Duration
+	WallTime       time.Duration
+	MemoryKB       int64
+	TimedOut       bool
+	OutputExceeded bool
+	// CPULimited is set when the process died of SIGXCPU, which the CPU
+	// time rlimit sends just before CPUTime is seen to pass the limit.
+	CPULimited bool
+	// Killed is set when the process died of SIGKILL. The hard CPU time
+	// rlimit sends it, but so do the kernel when memory runs out and the
+	// judge on wall timeouts and output overflows.
+	Killed bool
+	// OOMKilled is set when the kernel killed a process of the run for
+	// going over the memory limit of its cgroup.
+	OOMKilled bool
+}
+
+// oomMarkers are fragments of the messages common runtimes print when an
+// allocation fails under the data segment limit.
+var oomMarkers = []string{
+	"MemoryError",
+	"out of memory",
+	"bad_alloc",
+	"memory allocation of",
+	"Cannot allocate memory",
+}
+
+// verdict classifies the execution. It does not look at the output, so a
+// clean exit yields StatusPassed and the caller compares stdout afterwards.
+func (e *execution) verdict(limits Limits) string {
+	switch {
+	case e.OutputExceeded:
+		return StatusOutputLimitExceeded
+	case e.OOMKilled:
+		return StatusMemoryLimitExceeded
+	case e.TimedOut || e.CPULimited || e.CPUTime > limits.Time:
+		return StatusTimeLimitExceeded
+	case e.Killed && e.CPUTime >= limits.Time:
+		return StatusTimeLimitExceeded
+	case e.MemoryKB<<10 > limits.Memory:
+		return StatusMemoryLimitExceeded
+	case e.Err != nil:
+		for _, marker := range oomMarkers {
+			if strings.Contains(e.Stderr, marker) {
+				return StatusMemoryLimitExceeded
+			}
+		}
+		return StatusRuntimeError
+	}
+	return StatusPassed
+}
+
+// box is what a sandboxed command may touch: its working directory and
+// the directories mounted into its private root. Where the judge cannot
+// build such a root, only the environment is restricted.
+type box struct {
+	Dir    string
+	Mounts []mount
+	// Home is HOME and TMPDIR, Dir if empty.
+	Home string
+	// Cache is mounted writable and set as XDG_CACHE_HOME, where
+	// toolchains such as Go keep their build cache.
+	Cache string
+}
+
+type mount struct {
+	Path     string `json:"path"`
+	Writable bool   `json:"writable"`
+	// Shared mounts are written by runs of different UIDs. They are not
+	// chowned to the run's UID, and its umask is cleared so that what it
+	// creates there stays writable for the next.
+	Shared bool `json:"shared,omitempty"`
+}
+
+// workspace is a box of a single writable directory.
+func workspace(dir string) box {
+	return box{Dir: dir, Mounts: []mount{{Path: dir, Writable: true}}}
+}
+
+// environ is the whole environment of a sandboxed command. Nothing of the
+// server's own environment, which holds its secrets, is passed on besides
+// PATH.
+func (b box) environ() []string {
+	home := b.Home
+	if home == "" {
+		home = b.Dir
+	}
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + home,
+		"TMPDIR=" + home,
+		"LANG=C.UTF-8",
+	}
+	if b.Cache != "" {
+		env = append(env, "XDG_CACHE_HOME="+b.Cache)
+	}
+	return env
+}
+
+func (b box) mounts() []mount {
+	if b.Cache == "" {
+		return b.Mounts
+	}
+	return append(append([]mount(nil), b.Mounts...), mount{Path: b.Cache, Writable: true, Shared: true})
+}
+
+// execute runs argv in b under limits, feeding stdin and capturing at
+// most limits.Output bytes of each output stream. Exceeding the wall time or
+// the output limit on stdout kills the whole process group; stderr is only
+// truncated.
+func execute(ctx context.Context, b box, argv []string, stdin string, limits Limits) (*execution, error) {
+	ctx, cancel := context.WithTimeout(ctx, limits.wallTime())
+	defer cancel()
+
+	sb, err := newSandbox(ctx, b, argv, limits)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.release()
+	cmd := sb.cmd
+
+	stdout := &limitedBuffer{limit: limits.Output, onExceed: cancel}
+	stderr := &limitedBuffer{limit: limits.Output}
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	runErr := cmd.Run()
+	res := &execution{
+		Stdout:         stdout.String(),
+		Stderr:         stderr.String(),
+		Err:            runErr,
+		WallTime:       time.Since(start),
+		OutputExceeded: stdout.exceeded,
+		TimedOut:       errors.Is(ctx.Err(), context.DeadlineExceeded),
+	}
+	if cmd.ProcessState != nil {
+		sb.account(cmd.ProcessState, res)
+	} else if runErr != nil && !res.TimedOut {
+		return nil, runErr
+	}
+
+	return res, nil
+}
+
+// compileLimits bound a build. Compilers run several processes and write
+// binaries much larger than any program output.
+var compileLimits = Limits{
+	Time:      compileTimeout,
+	WallTime:  compileTimeout,
+	Memory:    1 << 30,
+	Output:    64 << 10,
+	FileSize:  256 << 20,
+	Processes: 256,
+}
+
+// compile runs a build command in dir through the sandbox, returning the
+// compiler's output and whether the build succeeded. The error is only set
+// when the command could not be run at all.
+func compile(ctx context.Context, dir string, argv []string) (string, bool, error) {
+	b := workspace(dir)
+	b.Cache = compileCache()
+	res, err := execute(ctx, b, argv, "", compileLimits)
+	if err != nil {
+		return "", false, err
+	}
+
+	out := strings.TrimSpace(res.Stdout + res.Stderr)
+	switch status := res.verdict(compileLimits); status {
+	case StatusPassed:
+		return out, true, nil
+	case StatusRuntimeError:
+		return out, false, nil
+	default:
+		return strings.TrimSpace(out + "\ncompilation stopped: " + status), false, nil
+	}
+}
+
+// compileCache is the build cache shared by every compile, or empty if it
+// cannot be created. Programs never see it, so they cannot plant anything
+// in it for later builds.
+var compileCache = sync.OnceValue(func() string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	dir := filepath.Join(base, "codelearn-judge")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return ""
+	}
+	// Builds run as different UIDs.
+	if err := os.Chmod(dir, 0o777); err != nil {
+		return ""
+	}
+	return dir
+})
+
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	exceeded bool
+	onExceed func()
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - int64(b.buf.Len()); int64(len(p)) > remaining {
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		if !b.exceeded && b.onExceed != nil {
+			b.onExceed()
+		}
+		b.exceeded = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
+
+// normalize trims trailing whitespace on every line and surrounding blank
+// lines so that a missing final newline does not fail an otherwise correct
+// answer.
+func normalize(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,466 @@
+//go:build linux
+
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxEnv carries the JSON encoded sandboxSpec to the helper. It is the
+// only variable the helper is started with.
+const sandboxEnv = "CODELEARN_SANDBOX"
+
+// HelperName is the file name the sandbox helper is looked up under.
+const HelperName = "codelearn-sandbox"
+
+// DefaultSandboxPaths are the system directories mounted read-only into
+// the root of every sandboxed program.
+var DefaultSandboxPaths = []string{"/bin", "/etc", "/lib", "/lib32", "/lib64", "/libx32", "/sbin", "/usr"}
+
+// SandboxConfig configures how the judge isolates the programs it runs.
+type SandboxConfig struct {
+	// Helper is the path of the sandbox helper binary built from
+	// ./sandbox. Empty looks for HelperName next to the server executable
+	// and then in PATH.
+	Helper string
+	// UIDBase and UIDCount delimit the user IDs programs run as when the
+	// server runs as root. Every run gets an ID of its own, so programs
+	// can neither signal nor read each other.
+	UIDBase  int
+	UIDCount int
+	// Paths are the directories visible read-only to programs besides
+	// their workspace, DefaultSandboxPaths if empty.
+	Paths []string
+	// Cgroup is the name of the judge's cgroup, DefaultCgroup if empty.
+	// Every run gets a cgroup below it limiting its processes and memory.
+	Cgroup string
+	// AllowUnsandboxed lets the judge start when it cannot isolate
+	// programs because the server does not run as root. They then run as
+	// the server's user, able to read its files and use the network.
+	AllowUnsandboxed bool
+}
+
+// sandboxSpec is everything the helper needs to know about one run.
+type sandboxSpec struct {
+	Limits Limits   `json:"limits"`
+	Dir    string   `json:"dir"`
+	Env    []string `json:"env"`
+	// Isolate is set when the server runs as root. The helper is then
+	// started in new mount, network and IPC namespaces, builds a root from
+	// Paths and Mounts in the empty directory Root and drops to UID.
+	Isolate bool     `json:"isolate"`
+	Root    string   `json:"root,omitempty"`
+	Paths   []string `json:"paths,omitempty"`
+	Mounts  []mount  `json:"mounts,omitempty"`
+	UID     int      `json:"uid,omitempty"`
+	// Cgroups are the cgroup.procs files of the run's cgroup. The helper
+	// joins it just before exec, so that the memory it used itself stays
+	// charged to the server.
+	Cgroups []string `json:"cgroups,omitempty"`
+}
+
+var sandboxSetup struct {
+	helper  string
+	isolate bool
+	paths   []string
+	uids    chan int
+	cgroups *cgroupRoot
+}
+
+// SetupSandbox locates the helper and decides how programs are isolated.
+// It must be called before the first job runs and returns a description
+// of the isolation for the server log and whether programs are isolated
+// from the server at all. Without root that is only allowed with
+// cfg.AllowUnsandboxed; otherwise ErrNotIsolated is returned.
+func SetupSandbox(cfg SandboxConfig) (string, bool, error) {
+	helper, err := findHelper(cfg.Helper)
+	if err != nil {
+		return "", false, err
+	}
+	sandboxSetup.helper = helper
+	sandboxSetup.paths = cfg.Paths
+	if len(sandboxSetup.paths) == 0 {
+		sandboxSetup.paths = DefaultSandboxPaths
+	}
+
+	name := cfg.Cgroup
+	if name == "" {
+		name = DefaultCgroup
+	}
+	limited := "a cgroup per run limits processes and memory"
+	if sandboxSetup.cgroups, err = setupCgroups(name); err != nil {
+		limited = fmt.Sprintf("no cgroups (%v), so memory is limited per process", err)
+	}
+
+	if os.Geteuid() != 0 {
+		if !cfg.AllowUnsandboxed {
+			return "", false, ErrNotIsolated
+		}
+		return fmt.Sprintf("helper %s; %s; not running as root, so programs run as the server's user and can read its files", helper, limited), false, nil
+	}
+	if cfg.UIDBase <= 0 || cfg.UIDCount <= 0 {
+		return "", false, errors.New("sandbox: a range of user IDs is required when running as root")
+	}
+	sandboxSetup.isolate = true
+	sandboxSetup.uids = make(chan int, cfg.UIDCount)
+	for uid := cfg.UIDBase; uid < cfg.UIDBase+cfg.UIDCount; uid++ {
+		sandboxSetup.uids <- uid
+	}
+	return fmt.Sprintf("helper %s; %s; programs run as UIDs %d-%d in private mount and network namespaces",
+		helper, limited, cfg.UIDBase, cfg.UIDBase+cfg.UIDCount-1), true, nil
+}
+
+func findHelper(path string) (string, error) {
+	if path == "" {
+		if self, err := os.Executable(); err == nil {
+			path = filepath.Join(filepath.Dir(self), HelperName)
+		}
+		if _, err := os.Stat(path); err != nil {
+			path = HelperName
+		}
+	}
+	helper, err := exec.LookPath(path)
+	if err != nil {
+		return "", fmt.Errorf("sandbox helper not found, build it with go build -o %s ./sandbox: %w", HelperName, err)
+	}
+	return filepath.Abs(helper)
+}
+
+// sandbox is one command started through the helper.
+type sandbox struct {
+	cmd     *exec.Cmd
+	release func()
+	cgroup  *cgroup
+}
+
+// newSandbox prepares argv to run through the helper, which applies the
+// limits and isolation to itself and then execs argv. Setting rlimits
+// between fork and exec is not possible with os/exec, and applying them to
+// the child after it started would leave a window for a fork bomb.
+func newSandbox(ctx context.Context, b box, argv []string, limits Limits) (*sandbox, error) {
+	if sandboxSetup.helper == "" {
+		return nil, errors.New("sandbox: SetupSandbox was not called")
+	}
+
+	spec := sandboxSpec{Limits: limits, Dir: b.Dir, Env: b.environ(), Mounts: b.mounts()}
+	sb := &sandbox{release: func() {}}
+	attr := &syscall.SysProcAttr{Setpgid: true}
+
+	if sandboxSetup.isolate {
+		var uid int
+		select {
+		case uid = <-sandboxSetup.uids:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		root, err := os.MkdirTemp("", "codelearn-root-")
+		if err != nil {
+			sandboxSetup.uids <- uid
+			return nil, err
+		}
+		sb.release = func() {
+			os.Remove(root)
+			sandboxSetup.uids <- uid
+		}
+
+		spec.Isolate, spec.Root, spec.Paths, spec.UID = true, root, sandboxSetup.paths, uid
+		attr.Cloneflags = syscall.CLONE_NEWNS | syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC
+	}
+
+	if sandboxSetup.cgroups != nil {
+		cg, err := sandboxSetup.cgroups.create(limits)
+		if err != nil {
+			sb.release()
+			return nil, err
+		}
+		release := sb.release
+		sb.cgroup, spec.Cgroups = cg, cg.procs()
+		sb.release = func() {
+			cg.destroy()
+			release()
+		}
+	}
+
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		sb.release()
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, sandboxSetup.helper, argv...)
+	cmd.Env = []string{sandboxEnv + "=" + string(encoded)}
+	cmd.SysProcAttr = attr
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	sb.cmd = cmd
+	return sb, nil
+}
+
+// SandboxMain is the sandbox helper: it applies the spec in its
+// environment to itself and execs its arguments. It never returns.
+func SandboxMain() {
+	fail := func(err error) {
+		fmt.Fprintln(os.Stderr, "sandbox:", err)
+		os.Exit(127)
+	}
+
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(os.Getenv(sandboxEnv)), &spec); err != nil {
+		fail(fmt.Errorf("invalid spec: %w", err))
+	}
+	argv := os.Args[1:]
+	if len(argv) == 0 {
+		fail(errors.New("no command"))
+	}
+
+	// The cgroup filesystem is out of sight once isolated.
+	join := func() error { return nil }
+	if len(spec.Cgroups) > 0 {
+		var err error
+		if join, err = joinCgroups(spec.Cgroups); err != nil {
+			fail(err)
+		}
+	}
+	if spec.Isolate {
+		if err := isolate(spec); err != nil {
+			fail(err)
+		}
+	}
+	if err := os.Chdir(spec.Dir); err != nil {
+		fail(err)
+	}
+
+	cpu := uint64((spec.Limits.Time + time.Second - 1) / time.Second)
+	type rlimit struct {
+		resource int
+		cur, max uint64
+	}
+	rlimits := []rlimit{
+		{unix.RLIMIT_CPU, cpu, cpu + 1},
+		{unix.RLIMIT_FSIZE, uint64(spec.Limits.fileSize()), uint64(spec.Limits.fileSize())},
+		{unix.RLIMIT_CORE, 0, 0},
+	}
+	// Without a cgroup, memory can only be limited per process. The
+	// kernel counts processes per user and ignores the limit for root,
+	// so it only means something for a user of the run's own.
+	if len(spec.Cgroups) == 0 {
+		rlimits = append(rlimits, rlimit{unix.RLIMIT_DATA, uint64(spec.Limits.Memory), uint64(spec.Limits.Memory)})
+		if spec.Isolate {
+			rlimits = append(rlimits, rlimit{unix.RLIMIT_NPROC, uint64(spec.Limits.Processes), uint64(spec.Limits.Processes)})
+		}
+	}
+	for _, rl := range rlimits {
+		if err := unix.Setrlimit(rl.resource, &unix.Rlimit{Cur: rl.cur, Max: rl.max}); err != nil {
+			fail(err)
+		}
+	}
+
+	if err := join(); err != nil {
+		fail(err)
+	}
+	for _, m := range spec.Mounts {
+		if m.Shared {
+			syscall.Umask(0)
+		}
+	}
+
+	if spec.Isolate {
+		if err := dropPrivileges(spec.UID); err != nil {
+			fail(err)
+		}
+	}
+
+	for _, kv := range spec.Env {
+		if path, ok := strings.CutPrefix(kv, "PATH="); ok {
+			os.Setenv("PATH", path)
+		}
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		fail(err)
+	}
+
+	fail(syscall.Exec(path, argv, spec.Env))
+}
+
+// isolate builds the program's root in spec.Root: the system paths and
+// /dev nodes it needs read-only, a fresh /proc showing only its own
+// processes, an empty /tmp and the box mounts at their usual paths. It
+// then chroots into it. The helper runs in a mount namespace of its own,
+// so none of this is visible outside.
+func isolate(spec sandboxSpec) error {
+	// The working directory inherited from the server is where its
+	// database and .env live; hide it should a system path contain it.
+	serverDir, _ := os.Getwd()
+
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	root := spec.Root
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=755,size=1m"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	for _, path := range spec.Paths {
+		if err := bindSystemPath(root, path); err != nil {
+			return err
+		}
+	}
+
+	// Checked before /tmp is mounted, which hides anything below it
+	// anyway and must stay writable.
+	if serverDir != "" && serverDir != "/" {
+		if fi, err := os.Stat(filepath.Join(root, serverDir)); err == nil && fi.IsDir() {
+			if err := unix.Mount("tmpfs", filepath.Join(root, serverDir), "tmpfs", unix.MS_RDONLY, "mode=000"); err != nil {
+				return fmt.Errorf("hide %s: %w", serverDir, err)
+			}
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(root, "dev"), 0o755); err != nil {
+		return err
+	}
+	for _, dev := range []string{"null", "zero", "full", "random", "urandom"} {
+		if err := bind(root, filepath.Join("/dev", dev), false); err != nil {
+			return err
+		}
+	}
+	for name, target := range map[string]string{
+		"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, "dev", name)); err != nil {
+			return err
+		}
+	}
+
+	proc := filepath.Join(root, "proc")
+	if err := os.Mkdir(proc, 0o555); err != nil {
+		return err
+	}
+	if err := unix.Mount("proc", proc, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "hidepid=2"); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+	tmp := filepath.Join(root, "tmp")
+	if err := os.Mkdir(tmp, 0o755); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", tmp, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777,size=16m"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+
+	for _, m := range spec.Mounts {
+		if m.Writable && !m.Shared {
+			if err := os.Chown(m.Path, spec.UID, spec.UID); err != nil {
+				return err
+			}
+		}
+		if err := bind(root, m.Path, m.Writable); err != nil {
+			return err
+		}
+	}
+
+	if err := unix.Mount("", root, "", unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("remount root: %w", err)
+	}
+	if err := unix.Chroot(root); err != nil {
+		return fmt.Errorf("chroot: %w", err)
+	}
+	return os.Chdir("/")
+}
+
+// bindSystemPath makes a system path visible in root. Symbolic links such
+// as /bin -> usr/bin on merged /usr systems are copied rather than bound.
+func bindSystemPath(root, path string) error {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, filepath.Join(root, path))
+	}
+	return bind(root, path, false)
+}
+
+// bind mounts path at the same path inside root, read-only unless
+// writable.
+func bind(root, path string, writable bool) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(root, path)
+	if fi.IsDir() {
+		err = os.MkdirAll(target, 0o755)
+	} else {
+		if err = os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+			err = os.WriteFile(target, nil, 0o644)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := unix.Mount(path, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", path, err)
+	}
+	flags := uintptr(unix.MS_REMOUNT | unix.MS_BIND | unix.MS_NOSUID)
+	if !writable {
+		flags |= unix.MS_RDONLY
+	}
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("remount %s: %w", path, err)
+	}
+	return nil
+}
+
+// dropPrivileges switches to uid and a group of the same ID, with no
+// supplementary groups. Unlike their x/sys/unix counterparts, the syscall
+// package functions apply to every thread of the process.
+func dropPrivileges(uid int) error {
+	if err := syscall.Setgroups(nil); err != nil {
+		return err
+	}
+	if err := syscall.Setresgid(uid, uid, uid); err != nil {
+		return err
+	}
+	return syscall.Setresuid(uid, uid, uid)
+}
+
+// account records the resources the finished run used. The peak memory of
+// its cgroup leaves out the helper, which ru_maxrss, the fallback without
+// cgroups, includes.
+func (sb *sandbox) account(state *os.ProcessState, res *execution) {
+	res.CPUTime = state.UserTime() + state.SystemTime()
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		res.CPULimited = status.Signal() == syscall.SIGXCPU
+		res.Killed = status.Signal() == syscall.SIGKILL
+	}
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		res.MemoryKB = rusage.Maxrss
+	}
+	if sb.cgroup != nil {
+		if peak := sb.cgroup.peakKB(); peak >= 0 {
+			res.MemoryKB = peak
+		}
+		res.OOMKilled = sb.cgroup.oomKilled()
+	}
+}
@@ -0,0 +1,56 @@
+//go:build !linux
+
+package judge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// SandboxConfig is accepted for portability; programs are not isolated on
+// platforms other than Linux.
+type SandboxConfig struct {
+	Helper   string
+	UIDBase  int
+	UIDCount int
+	Paths    []string
+	Cgroup   string
+	// AllowUnsandboxed must be set for the judge to start at all.
+	AllowUnsandboxed bool
+}
+
+func SetupSandbox(cfg SandboxConfig) (string, bool, error) {
+	if !cfg.AllowUnsandboxed {
+		return "", false, ErrNotIsolated
+	}
+	return "no isolation on this platform; only the wall time and output limits are enforced", false, nil
+}
+
+type sandbox struct {
+	cmd     *exec.Cmd
+	release func()
+}
+
+// newSandbox runs argv directly with the restricted environment. Only the
+// wall time and output limits are enforced on platforms without rlimit
+// support in the judge.
+func newSandbox(ctx context.Context, b box, argv []string, limits Limits) (*sandbox, error) {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = b.Dir
+	cmd.Env = b.environ()
+	return &sandbox{cmd: cmd, release: func() {}}, nil
+}
+
+// SandboxMain is the sandbox helper, which only exists on Linux.
+func SandboxMain() {
+	fmt.Fprintln(os.Stderr, "sandbox: not supported on this platform")
+	os.Exit(127)
+}
+
+// account records the CPU time of the finished run. Memory is not
+// measured on this platform.
+func (sb *sandbox) account(state *os.ProcessState, res *execution) {
+	res.CPUTime = state.UserTime() + state.SystemTime()
+}
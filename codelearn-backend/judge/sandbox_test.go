@@ -0,0 +1,61 @@
+package judge
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerdict(t *testing.T) {
+	limits := Limits{Time: time.Second, Memory: 64 << 20, Output: 1 << 10}
+	exitErr := errors.New("exit status 1")
+
+	tests := []struct {
+		name string
+		exec execution
+		want string
+	}{
+		{"clean exit", execution{CPUTime: 100 * time.Millisecond, MemoryKB: 1024}, StatusPassed},
+		{"non-zero exit", execution{Err: exitErr}, StatusRuntimeError},
+		{"wall timeout", execution{Err: exitErr, TimedOut: true}, StatusTimeLimitExceeded},
+		{"cpu time over the limit", execution{CPUTime: 1100 * time.Millisecond}, StatusTimeLimitExceeded},
+		{"cpu time at the limit", execution{CPUTime: time.Second}, StatusPassed},
+		{"killed by the cpu rlimit below the measured limit", execution{Err: exitErr, CPULimited: true, CPUTime: 990 * time.Millisecond}, StatusTimeLimitExceeded},
+		{"killed by the oom killer", execution{Err: exitErr, OOMKilled: true, CPULimited: true}, StatusMemoryLimitExceeded},
+		{"sigkill at the cpu limit", execution{Err: exitErr, Killed: true, CPUTime: time.Second}, StatusTimeLimitExceeded},
+		{"sigkill below the cpu limit", execution{Err: exitErr, Killed: true, CPUTime: 200 * time.Millisecond}, StatusRuntimeError},
+		{"sigkill below the cpu limit over memory", execution{Err: exitErr, Killed: true, CPUTime: 200 * time.Millisecond, MemoryKB: 65 << 10}, StatusMemoryLimitExceeded},
+		{"peak memory over the limit", execution{MemoryKB: 65 << 10}, StatusMemoryLimitExceeded},
+		{"allocation failure message", execution{Err: exitErr, Stderr: "Traceback...\nMemoryError\n"}, StatusMemoryLimitExceeded},
+		{"allocation failure message on success", execution{Stderr: "MemoryError"}, StatusPassed},
+		{"output limit", execution{Err: exitErr, OutputExceeded: true, TimedOut: true}, StatusOutputLimitExceeded},
+		{"output limit before oom", execution{Err: exitErr, OutputExceeded: true, OOMKilled: true}, StatusOutputLimitExceeded},
+		{"timeout before memory", execution{TimedOut: true, MemoryKB: 65 << 10}, StatusTimeLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec.verdict(limits); got != tt.want {
+				t.Errorf("verdict() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"3", "3"},
+		{"3\n", "3"},
+		{"1 2  \r\n3\t\n\n", "1 2\n3"},
+		{"\n\na\n b\n", "a\n b"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalize(tt.in); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package judge
+
+import "strconv"
+
+// Scoring policies selectable per challenge. Scores are always normalised
+// to 0-100.
+const (
+	// PolicyBinary awards full marks only when every test passes.
+	PolicyBinary = "binary"
+	// PolicyProportional awards the weight of every passed test.
+	PolicyProportional = "proportional"
+	// PolicySubtask awards the weight of a group only when every test in
+	// the group passes, as in IOI style subtasks. Tests without a group
+	// form a group of their own.
+	PolicySubtask = "subtask"
+)
+
+// MaxScore is the score of a fully correct submission.
+const MaxScore = 100
+
+// GroupScore is the breakdown of one subtask group. Points and MaxPoints
+// are in test weight units; the overall score is scaled to MaxScore.
+type GroupScore struct {
+	Group     string `json:"group"`
+	Passed    int    `json:"passed"`
+	Total     int    `json:"total"`
+	Points    int    `json:"points"`
+	MaxPoints int    `json:"max_points"`
+}
+
+// ValidPolicy reports whether policy names a known scoring policy.
+func ValidPolicy(policy string) bool {
+	switch policy {
+	case PolicyBinary, PolicyProportional, PolicySubtask:
+		return true
+	}
+	return false
+}
+
+// Score computes the overall score and per-group breakdown of results
+// against testCases under policy. Unknown policies score proportionally.
+func Score(policy string, testCases []TestCase, results []TestResult) (int, []GroupScore) {
+	passed := make([]bool, len(testCases))
+	allPassed := len(results) == len(testCases)
+	for _, tr := range results {
+		passed[tr.Index] = tr.Verdict == StatusPassed
+		allPassed = allPassed && passed[tr.Index]
+	}
+
+	var groups []GroupScore
+	index := map[string]int{}
+	for i, tc := range testCases {
+		name := tc.Group
+		if name == "" {
+			name = ungroupedName(i)
+		}
+		g, ok := index[name]
+		if !ok {
+			g = len(groups)
+			index[name] = g
+			groups = append(groups, GroupScore{Group: name})
+		}
+
+		weight := tc.weight()
+		groups[g].Total++
+		groups[g].MaxPoints += weight
+		if passed[i] {
+			groups[g].Passed++
+			groups[g].Points += weight
+		}
+	}
+
+	var points, maxPoints int
+	for i := range groups {
+		g := &groups[i]
+		switch policy {
+		case PolicyBinary:
+			if !allPassed {
+				g.Points = 0
+			}
+		case PolicySubtask:
+			if g.Passed != g.Total {
+				g.Points = 0
+			}
+		}
+		points += g.Points
+		maxPoints += g.MaxPoints
+	}
+
+	if maxPoints == 0 {
+		return 0, groups
+	}
+	return points * MaxScore / maxPoints, groups
+}
+
+func ungroupedName(i int) string {
+	return "test " + strconv.Itoa(i+1)
+}
@@ -0,0 +1,95 @@
+package judge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	// Two subtasks worth 2 and 6 points and an ungrouped test worth 2.
+	testCases := []TestCase{
+		{Group: "small", Weight: 1},
+		{Group: "small", Weight: 1},
+		{Group: "large", Weight: 3},
+		{Group: "large", Weight: 3},
+		{Weight: 2},
+	}
+	results := func(verdicts ...string) []TestResult {
+		var rs []TestResult
+		for i, v := range verdicts {
+			rs = append(rs, TestResult{Index: i, Verdict: v})
+		}
+		return rs
+	}
+	allPassed := results(StatusPassed, StatusPassed, StatusPassed, StatusPassed, StatusPassed)
+	oneLargeFailed := results(StatusPassed, StatusPassed, StatusPassed, StatusWrongAnswer, StatusPassed)
+
+	tests := []struct {
+		name    string
+		policy  string
+		results []TestResult
+		want    int
+	}{
+		{"binary all passed", PolicyBinary, allPassed, 100},
+		{"binary one failed", PolicyBinary, oneLargeFailed, 0},
+		{"binary stopped early", PolicyBinary, allPassed[:3], 0},
+		{"proportional all passed", PolicyProportional, allPassed, 100},
+		{"proportional one failed", PolicyProportional, oneLargeFailed, 70},
+		{"proportional nothing judged", PolicyProportional, nil, 0},
+		{"subtask all passed", PolicySubtask, allPassed, 100},
+		{"subtask one failed loses its group", PolicySubtask, oneLargeFailed, 40},
+		{"unknown policy scores proportionally", "unknown", oneLargeFailed, 70},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := Score(tt.policy, testCases, tt.results); got != tt.want {
+				t.Errorf("Score() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreGroups(t *testing.T) {
+	testCases := []TestCase{
+		{Group: "a"},
+		{Weight: 4},
+		{Group: "a", Weight: 2},
+	}
+	results := []TestResult{
+		{Index: 0, Verdict: StatusPassed},
+		{Index: 1, Verdict: StatusPassed},
+		{Index: 2, Verdict: StatusTimeLimitExceeded},
+	}
+
+	tests := []struct {
+		policy string
+		score  int
+		groups []GroupScore
+	}{
+		{PolicyProportional, 71, []GroupScore{
+			{Group: "a", Passed: 1, Total: 2, Points: 1, MaxPoints: 3},
+			{Group: "test 2", Passed: 1, Total: 1, Points: 4, MaxPoints: 4},
+		}},
+		{PolicySubtask, 57, []GroupScore{
+			{Group: "a", Passed: 1, Total: 2, Points: 0, MaxPoints: 3},
+			{Group: "test 2", Passed: 1, Total: 1, Points: 4, MaxPoints: 4},
+		}},
+		{PolicyBinary, 0, []GroupScore{
+			{Group: "a", Passed: 1, Total: 2, Points: 0, MaxPoints: 3},
+			{Group: "test 2", Passed: 1, Total: 1, Points: 0, MaxPoints: 4},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			score, groups := Score(tt.policy, testCases, results)
+			if score != tt.score {
+				t.Errorf("score = %d, want %d", score, tt.score)
+			}
+			if !reflect.DeepEqual(groups, tt.groups) {
+				t.Errorf("groups = %+v, want %+v", groups, tt.groups)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bucket is a token bucket holding at most limit tokens, refilled
+// continuously over the window.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimitMiddleware allows each caller limit requests per window, with
+// bursts of up to limit. Callers are identified by the user ID set by
+// AuthMiddleware, or by client IP on public routes.
+func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
+	var mu sync.Mutex
+	buckets := map[string]*bucket{}
+	rate := float64(limit) / window.Seconds()
+
+	return func(c *gin.Context) {
+		key := c.ClientIP()
+		if userID, exists := c.Get("user_id"); exists {
+			key = fmt.Sprint("user:", userID)
+		}
+
+		now := time.Now()
+		mu.Lock()
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{tokens: float64(limit), last: now}
+			buckets[key] = b
+		}
+		b.tokens = math.Min(float64(limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+		b.last = now
+
+		allowed := b.tokens >= 1
+		if allowed {
+			b.tokens--
+		}
+		wait := (1 - b.tokens) / rate
+
+		// Buckets that have refilled completely carry no state worth keeping.
+		if len(buckets) > 10000 {
+			for k, other := range buckets {
+				if now.Sub(other.last) > window {
+					delete(buckets, k)
+				}
+			}
+		}
+		mu.Unlock()
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded, try again later"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
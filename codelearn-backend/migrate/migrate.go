@@ -2,10 +2,19 @@ package main
 
 import (
 	"codelearn-backend/db"
+	"codelearn-backend/models"
+	"codelearn-backend/stats"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
 )
 
 func main() {
+	if err := db.InitDB(); err != nil {
+		log.Fatal(err)
+	}
+
 	if err := db.DB.Ping(); err != nil {
 		log.Fatal(err)
 	}
@@ -14,9 +23,25 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if err := createSearchIndex(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := migrateTestCases(); err != nil {
+		log.Fatal(err)
+	}
+
 	if err := insertSampleData(); err != nil {
 		log.Fatal(err)
 	}
+
+	if err := backfillRevisions(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := stats.Rebuild(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func createTables() error {
@@ -26,6 +51,7 @@ func createTables() error {
 		username TEXT UNIQUE NOT NULL,
 		email TEXT UNIQUE NOT NULL,
 		password TEXT NOT NULL,
+		role TEXT DEFAULT 'student',
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);`
@@ -37,9 +63,23 @@ func createTables() error {
 		description TEXT NOT NULL,
 		difficulty TEXT NOT NULL,
 		language TEXT NOT NULL,
-		test_cases TEXT NOT NULL,
+		time_limit_ms INTEGER DEFAULT 2000,
+		memory_limit_kb INTEGER DEFAULT 262144,
+		output_limit_bytes INTEGER DEFAULT 65536,
+		max_processes INTEGER DEFAULT 64,
+		scoring_policy TEXT DEFAULT 'proportional',
+		checker TEXT DEFAULT 'exact',
+		checker_tolerance REAL DEFAULT 0,
+		checker_language TEXT DEFAULT '',
+		checker_code TEXT DEFAULT '',
+		signature TEXT DEFAULT '',
+		author_id INTEGER,
+		status TEXT DEFAULT 'published',
+		revision INTEGER DEFAULT 0,
+		deleted_at DATETIME,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (author_id) REFERENCES users (id)
 	);`
 
 	submissionTable := `
@@ -52,21 +92,565 @@ func createTables() error {
 		status TEXT DEFAULT 'pending',
 		score INTEGER DEFAULT 0,
 		output TEXT,
+		challenge_revision INTEGER DEFAULT 0,
+		contest_id INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users (id),
+		FOREIGN KEY (challenge_id) REFERENCES challenges (id),
+		FOREIGN KEY (contest_id) REFERENCES contests (id)
+	);`
+
+	submissionResultTable := `
+	CREATE TABLE IF NOT EXISTS submission_results (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		submission_id INTEGER NOT NULL,
+		test_index INTEGER NOT NULL,
+		verdict TEXT NOT NULL,
+		time_ms INTEGER DEFAULT 0,
+		memory_kb INTEGER DEFAULT 0,
+		hidden BOOLEAN DEFAULT 0,
+		input TEXT,
+		expected TEXT,
+		output TEXT,
+		stderr TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (submission_id) REFERENCES submissions (id),
+		UNIQUE (submission_id, test_index)
+	);`
+
+	testCaseTable := `
+	CREATE TABLE IF NOT EXISTS test_cases (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		challenge_id INTEGER NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		input TEXT NOT NULL,
+		expected TEXT NOT NULL,
+		hidden BOOLEAN DEFAULT 0,
+		weight INTEGER DEFAULT 1,
+		group_name TEXT DEFAULT '',
+		time_limit_ms INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (challenge_id) REFERENCES challenges (id)
+	);`
+
+	submissionGroupTable := `
+	CREATE TABLE IF NOT EXISTS submission_groups (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		submission_id INTEGER NOT NULL,
+		group_name TEXT NOT NULL,
+		passed INTEGER DEFAULT 0,
+		total INTEGER DEFAULT 0,
+		points INTEGER DEFAULT 0,
+		max_points INTEGER DEFAULT 0,
+		FOREIGN KEY (submission_id) REFERENCES submissions (id),
+		UNIQUE (submission_id, group_name)
+	);`
+
+	harnessTable := `
+	CREATE TABLE IF NOT EXISTS harnesses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		challenge_id INTEGER NOT NULL,
+		language TEXT NOT NULL,
+		template TEXT NOT NULL,
+		FOREIGN KEY (challenge_id) REFERENCES challenges (id),
+		UNIQUE (challenge_id, language)
+	);`
+
+	rejudgeTable := `
+	CREATE TABLE IF NOT EXISTS rejudges (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		challenge_id INTEGER,
+		submission_id INTEGER,
+		requested_by INTEGER NOT NULL,
+		total INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (challenge_id) REFERENCES challenges (id),
+		FOREIGN KEY (submission_id) REFERENCES submissions (id),
+		FOREIGN KEY (requested_by) REFERENCES users (id)
+	);`
+
+	rejudgeItemTable := `
+	CREATE TABLE IF NOT EXISTS rejudge_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		rejudge_id INTEGER NOT NULL,
+		submission_id INTEGER NOT NULL,
+		previous_status TEXT NOT NULL,
+		previous_score INTEGER DEFAULT 0,
+		new_status TEXT,
+		new_score INTEGER,
+		judged_at DATETIME,
+		FOREIGN KEY (rejudge_id) REFERENCES rejudges (id),
+		FOREIGN KEY (submission_id) REFERENCES submissions (id),
+		UNIQUE (rejudge_id, submission_id)
+	);`
+
+	refreshTokenTable := `
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		family_id TEXT NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		expires_at DATETIME NOT NULL,
+		used_at DATETIME,
+		revoked_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	);`
+
+	sessionTable := `
+	CREATE TABLE IF NOT EXISTS sessions (
+		id TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		user_agent TEXT DEFAULT '',
+		ip_address TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME NOT NULL,
+		revoked_at DATETIME,
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	);`
+
+	revokedTokenTable := `
+	CREATE TABLE IF NOT EXISTS revoked_tokens (
+		jti TEXT PRIMARY KEY,
+		expires_at DATETIME NOT NULL
+	);`
+
+	challengeRevisionTable := `
+	CREATE TABLE IF NOT EXISTS challenge_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		challenge_id INTEGER NOT NULL,
+		revision INTEGER NOT NULL,
+		editor_id INTEGER,
+		snapshot TEXT NOT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (challenge_id) REFERENCES challenges (id),
+		FOREIGN KEY (editor_id) REFERENCES users (id),
+		UNIQUE (challenge_id, revision)
+	);`
+
+	tagTable := `
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE
+	);`
+
+	challengeTagTable := `
+	CREATE TABLE IF NOT EXISTS challenge_tags (
+		challenge_id INTEGER NOT NULL,
+		tag_id INTEGER NOT NULL,
+		PRIMARY KEY (challenge_id, tag_id),
+		FOREIGN KEY (challenge_id) REFERENCES challenges (id),
+		FOREIGN KEY (tag_id) REFERENCES tags (id)
+	);`
+
+	userBestScoreTable := `
+	CREATE TABLE IF NOT EXISTS user_best_scores (
+		user_id INTEGER NOT NULL,
+		challenge_id INTEGER NOT NULL,
+		score INTEGER NOT NULL,
+		solved BOOLEAN NOT NULL,
+		achieved_at DATETIME NOT NULL,
+		PRIMARY KEY (user_id, challenge_id),
 		FOREIGN KEY (user_id) REFERENCES users (id),
 		FOREIGN KEY (challenge_id) REFERENCES challenges (id)
 	);`
 
-	tables := []string{userTable, challengeTable, submissionTable}
+	userStatsTable := `
+	CREATE TABLE IF NOT EXISTS user_stats (
+		user_id INTEGER PRIMARY KEY,
+		total_score INTEGER NOT NULL,
+		challenges INTEGER NOT NULL,
+		solved INTEGER NOT NULL,
+		submissions INTEGER NOT NULL,
+		achieved_at DATETIME NOT NULL,
+		last_activity DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	);`
+
+	contestTable := `
+	CREATE TABLE IF NOT EXISTS contests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		start_time DATETIME NOT NULL,
+		end_time DATETIME NOT NULL,
+		scoring TEXT NOT NULL DEFAULT 'icpc',
+		penalty_minutes INTEGER DEFAULT 20,
+		freeze_minutes INTEGER DEFAULT 60,
+		created_by INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (created_by) REFERENCES users (id)
+	);`
+
+	contestChallengeTable := `
+	CREATE TABLE IF NOT EXISTS contest_challenges (
+		contest_id INTEGER NOT NULL,
+		challenge_id INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		points INTEGER DEFAULT 100,
+		PRIMARY KEY (contest_id, challenge_id),
+		FOREIGN KEY (contest_id) REFERENCES contests (id),
+		FOREIGN KEY (challenge_id) REFERENCES challenges (id)
+	);`
+
+	contestParticipantTable := `
+	CREATE TABLE IF NOT EXISTS contest_participants (
+		contest_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		registered_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		virtual_start DATETIME,
+		PRIMARY KEY (contest_id, user_id),
+		FOREIGN KEY (contest_id) REFERENCES contests (id),
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	);`
+
+	starterCodeTable := `
+	CREATE TABLE IF NOT EXISTS starter_code (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		challenge_id INTEGER NOT NULL,
+		language TEXT NOT NULL,
+		code TEXT NOT NULL,
+		FOREIGN KEY (challenge_id) REFERENCES challenges (id),
+		UNIQUE (challenge_id, language)
+	);`
+
+	classroomTable := `
+	CREATE TABLE IF NOT EXISTS classrooms (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		owner_id INTEGER NOT NULL,
+		invite_code TEXT UNIQUE NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (owner_id) REFERENCES users (id)
+	);`
+
+	classroomMemberTable := `
+	CREATE TABLE IF NOT EXISTS classroom_members (
+		classroom_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (classroom_id, user_id),
+		FOREIGN KEY (classroom_id) REFERENCES classrooms (id),
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	);`
+
+	assignmentTable := `
+	CREATE TABLE IF NOT EXISTS assignments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		classroom_id INTEGER NOT NULL,
+		title TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		due_at DATETIME NOT NULL,
+		late_policy TEXT NOT NULL DEFAULT 'accept',
+		late_penalty INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (classroom_id) REFERENCES classrooms (id)
+	);`
+
+	assignmentChallengeTable := `
+	CREATE TABLE IF NOT EXISTS assignment_challenges (
+		assignment_id INTEGER NOT NULL,
+		challenge_id INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (assignment_id, challenge_id),
+		FOREIGN KEY (assignment_id) REFERENCES assignments (id),
+		FOREIGN KEY (challenge_id) REFERENCES challenges (id)
+	);`
+
+	tables := []string{userTable, challengeTable, submissionTable, submissionResultTable, testCaseTable,
+		submissionGroupTable, harnessTable, rejudgeTable, rejudgeItemTable,
+		refreshTokenTable, sessionTable, revokedTokenTable, challengeRevisionTable,
+		starterCodeTable, tagTable, challengeTagTable, userBestScoreTable, userStatsTable,
+		contestTable, contestChallengeTable, contestParticipantTable,
+		classroomTable, classroomMemberTable, assignmentTable, assignmentChallengeTable}
 	for _, table := range tables {
 		if _, err := db.DB.Exec(table); err != nil {
 			return err
 		}
 	}
 
+	if err := addColumns(); err != nil {
+		return err
+	}
+	return createIndexes()
+}
+
+// createIndexes adds the indexes the leaderboard, stats, scoreboard and
+// classroom queries rely on.
+func createIndexes() error {
+	indexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_submissions_user_challenge ON submissions (user_id, challenge_id)",
+		"CREATE INDEX IF NOT EXISTS idx_submissions_challenge ON submissions (challenge_id)",
+		"CREATE INDEX IF NOT EXISTS idx_submissions_contest ON submissions (contest_id)",
+		"CREATE INDEX IF NOT EXISTS idx_user_stats_rank ON user_stats (total_score DESC, achieved_at)",
+		"CREATE INDEX IF NOT EXISTS idx_classroom_members_user ON classroom_members (user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_assignments_classroom ON assignments (classroom_id)",
+	}
+	for _, index := range indexes {
+		if _, err := db.DB.Exec(index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumns brings databases created before a column was introduced up to
+// date. CREATE TABLE IF NOT EXISTS leaves existing tables untouched, so new
+// columns on old tables have to be added here as well.
+func addColumns() error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"users", "role", "TEXT DEFAULT 'student'"},
+		{"challenges", "time_limit_ms", "INTEGER DEFAULT 2000"},
+		{"challenges", "memory_limit_kb", "INTEGER DEFAULT 262144"},
+		{"challenges", "output_limit_bytes", "INTEGER DEFAULT 65536"},
+		{"challenges", "max_processes", "INTEGER DEFAULT 64"},
+		{"challenges", "scoring_policy", "TEXT DEFAULT 'proportional'"},
+		{"challenges", "checker", "TEXT DEFAULT 'exact'"},
+		{"challenges", "checker_tolerance", "REAL DEFAULT 0"},
+		{"challenges", "checker_language", "TEXT DEFAULT ''"},
+		{"challenges", "checker_code", "TEXT DEFAULT ''"},
+		{"challenges", "signature", "TEXT DEFAULT ''"},
+		{"challenges", "author_id", "INTEGER REFERENCES users (id)"},
+		{"challenges", "status", "TEXT DEFAULT 'published'"},
+		{"challenges", "deleted_at", "DATETIME"},
+		{"challenges", "revision", "INTEGER DEFAULT 0"},
+		{"submissions", "challenge_revision", "INTEGER DEFAULT 0"},
+		{"submissions", "contest_id", "INTEGER REFERENCES contests (id)"},
+		{"contest_participants", "virtual_start", "DATETIME"},
+		{"test_cases", "group_name", "TEXT DEFAULT ''"},
+	}
+
+	for _, col := range columns {
+		exists, err := columnExists(col.table, col.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.DB.Exec("ALTER TABLE " + col.table + " ADD COLUMN " + col.column + " " + col.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateTestCases moves the JSON encoded challenges.test_cases column of
+// older databases into the test_cases table and drops the column.
+func migrateTestCases() error {
+	exists, err := columnExists("challenges", "test_cases")
+	if err != nil || !exists {
+		return err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, test_cases FROM challenges")
+	if err != nil {
+		return err
+	}
+
+	encoded := map[int]string{}
+	for rows.Next() {
+		var id int
+		var testCases string
+		if err := rows.Scan(&id, &testCases); err != nil {
+			rows.Close()
+			return err
+		}
+		encoded[id] = testCases
+	}
+	rows.Close()
+
+	for challengeID, testCasesJSON := range encoded {
+		var testCases []models.TestCase
+		if err := json.Unmarshal([]byte(testCasesJSON), &testCases); err != nil {
+			return fmt.Errorf("challenge %d: %w", challengeID, err)
+		}
+		if err := insertTestCases(tx, challengeID, testCases); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("ALTER TABLE challenges DROP COLUMN test_cases"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// createSearchIndex creates the full-text index over challenge titles and
+// descriptions, kept in sync by triggers, and rebuilds it. It needs FTS5, so
+// without the sqlite_fts5 build tag it is skipped and search falls back to
+// substring matching. The server must then be built without the tag too.
+// A database already indexed by an FTS5 build is refused instead, since its
+// triggers would make every write to challenges fail.
+func createSearchIndex() error {
+	if err := db.CheckSearchIndex(); err != nil {
+		return err
+	}
+	if !db.FTS5() {
+		log.Println("SQLite was built without FTS5, skipping the challenge search index")
+		return nil
+	}
+
+	statements := []string{`
+	CREATE VIRTUAL TABLE IF NOT EXISTS challenges_fts USING fts5(
+		title, description,
+		content = 'challenges', content_rowid = 'id',
+		tokenize = 'porter unicode61'
+	);`, `
+	CREATE TRIGGER IF NOT EXISTS challenges_fts_insert AFTER INSERT ON challenges BEGIN
+		INSERT INTO challenges_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+	END;`, `
+	CREATE TRIGGER IF NOT EXISTS challenges_fts_delete AFTER DELETE ON challenges BEGIN
+		INSERT INTO challenges_fts (challenges_fts, rowid, title, description)
+		VALUES ('delete', old.id, old.title, old.description);
+	END;`, `
+	CREATE TRIGGER IF NOT EXISTS challenges_fts_update AFTER UPDATE OF title, description ON challenges BEGIN
+		INSERT INTO challenges_fts (challenges_fts, rowid, title, description)
+		VALUES ('delete', old.id, old.title, old.description);
+		INSERT INTO challenges_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+	END;`,
+		`INSERT INTO challenges_fts (challenges_fts) VALUES ('rebuild');`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.DB.Exec(stmt); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// backfillRevisions records the current state of challenges created before
+// revisions existed, or inserted by insertSampleData, as their revision 1.
+func backfillRevisions() error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, title, description, difficulty, language,
+		       time_limit_ms, memory_limit_kb, output_limit_bytes, max_processes, scoring_policy,
+		       checker, checker_tolerance, checker_language, checker_code, signature
+		FROM challenges WHERE revision = 0
+	`)
+	if err != nil {
+		return err
+	}
+
+	snapshots := map[int]*models.ChallengeSnapshot{}
+	for rows.Next() {
+		var id int
+		var s models.ChallengeSnapshot
+		var signatureJSON string
+		err := rows.Scan(&id, &s.Title, &s.Description, &s.Difficulty, &s.Language,
+			&s.TimeLimitMS, &s.MemoryLimitKB, &s.OutputLimitBytes, &s.MaxProcesses, &s.ScoringPolicy,
+			&s.Checker, &s.CheckerTolerance, &s.CheckerLanguage, &s.CheckerCode, &signatureJSON)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if signatureJSON != "" {
+			s.Signature = &models.Signature{}
+			if err := json.Unmarshal([]byte(signatureJSON), s.Signature); err != nil {
+				rows.Close()
+				return fmt.Errorf("challenge %d: %w", id, err)
+			}
+		}
+		snapshots[id] = &s
+	}
+	rows.Close()
+
+	for challengeID, s := range snapshots {
+		tcRows, err := tx.Query(`
+			SELECT position, input, expected, hidden, weight, group_name, time_limit_ms
+			FROM test_cases WHERE challenge_id = ?
+			ORDER BY position, id
+		`, challengeID)
+		if err != nil {
+			return err
+		}
+		for tcRows.Next() {
+			var tc models.TestCase
+			err := tcRows.Scan(&tc.Position, &tc.Input, &tc.Expected, &tc.Hidden, &tc.Weight, &tc.Group, &tc.TimeLimitMS)
+			if err != nil {
+				tcRows.Close()
+				return err
+			}
+			s.TestCases = append(s.TestCases, tc)
+		}
+		tcRows.Close()
+
+		data, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO challenge_revisions (challenge_id, revision, snapshot) VALUES (?, 1, ?)",
+			challengeID, string(data))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE challenges SET revision = 1 WHERE id = ?", challengeID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func insertTestCases(tx *sql.Tx, challengeID int, testCases []models.TestCase) error {
+	for i, tc := range testCases {
+		weight := tc.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		_, err := tx.Exec(`
+			INSERT INTO test_cases (challenge_id, position, input, expected, hidden, weight, group_name, time_limit_ms)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, challengeID, i, tc.Input, tc.Expected, tc.Hidden, weight, tc.Group, tc.TimeLimitMS)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func columnExists(table, column string) (bool, error) {
+	rows, err := db.DB.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
 func insertSampleData() error {
 	var count int
 	err := db.DB.QueryRow("SELECT COUNT(*) FROM challenges").Scan(&count)
@@ -83,6 +667,7 @@ func insertSampleData() error {
 		description string
 		difficulty  string
 		language    string
+		signature   string
 		testCases   string
 	}{
 		{
@@ -90,20 +675,23 @@ func insertSampleData() error {
 			description: "Given an array of integers nums and an integer target, return indices of the two numbers such that they add up to target.",
 			difficulty:  "Easy",
 			language:    "python",
-			testCases:   `[{"input": "[2,7,11,15], 9", "expected": "[0,1]"}, {"input": "[3,2,4], 6", "expected": "[1,2]"}]`,
+			signature:   `{"function": "two_sum", "params": [{"name": "nums", "type": "int[]"}, {"name": "target", "type": "int"}], "returns": "int[]"}`,
+			testCases:   `[{"input": "[2,7,11,15], 9", "expected": "[0,1]"}, {"input": "[3,2,4], 6", "expected": "[1,2]"}, {"input": "[3,3], 6", "expected": "[0,1]", "hidden": true}]`,
 		},
 		{
 			title:       "Reverse String",
 			description: "Write a function that reverses a string. The input string is given as an array of characters s.",
 			difficulty:  "Easy",
 			language:    "javascript",
-			testCases:   `[{"input": "['h','e','l','l','o']", "expected": "['o','l','l','e','h']"}, {"input": "['H','a','n','n','a','h']", "expected": "['h','a','n','n','a','H']"}]`,
+			signature:   `{"function": "reverseString", "params": [{"name": "s", "type": "string[]"}], "returns": "string[]"}`,
+			testCases:   `[{"input": "[\"h\",\"e\",\"l\",\"l\",\"o\"]", "expected": "[\"o\",\"l\",\"l\",\"e\",\"h\"]"}, {"input": "[\"H\",\"a\",\"n\",\"n\",\"a\",\"h\"]", "expected": "[\"h\",\"a\",\"n\",\"n\",\"a\",\"H\"]"}]`,
 		},
 		{
 			title:       "Binary Search",
 			description: "Given an array of integers nums which is sorted in ascending order, and an integer target, write a function to search target in nums.",
 			difficulty:  "Medium",
 			language:    "go",
+			signature:   `{"function": "search", "params": [{"name": "nums", "type": "int[]"}, {"name": "target", "type": "int"}], "returns": "int"}`,
 			testCases:   `[{"input": "[-1,0,3,5,9,12], 9", "expected": "4"}, {"input": "[-1,0,3,5,9,12], 2", "expected": "-1"}]`,
 		},
 		{
@@ -111,26 +699,44 @@ func insertSampleData() error {
 			description: "Given a string s containing just the characters '(', ')', '{', '}', '[' and ']', determine if the input string is valid.",
 			difficulty:  "Easy",
 			language:    "python",
-			testCases:   `[{"input": "()", "expected": "true"}, {"input": "()[]{}", "expected": "true"}, {"input": "(]", "expected": "false"}]`,
+			signature:   `{"function": "is_valid", "params": [{"name": "s", "type": "string"}], "returns": "bool"}`,
+			testCases:   `[{"input": "\"()\"", "expected": "true"}, {"input": "\"()[]{}\"", "expected": "true"}, {"input": "\"(]\"", "expected": "false"}]`,
 		},
 		{
 			title:       "Fibonacci Sequence",
 			description: "Write a function to generate the nth Fibonacci number.",
 			difficulty:  "Easy",
 			language:    "javascript",
+			signature:   `{"function": "fib", "params": [{"name": "n", "type": "int"}], "returns": "int"}`,
 			testCases:   `[{"input": "0", "expected": "0"}, {"input": "1", "expected": "1"}, {"input": "10", "expected": "55"}]`,
 		},
 	}
 
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	for _, challenge := range challenges {
-		_, err := db.DB.Exec(`
-			INSERT INTO challenges (title, description, difficulty, language, test_cases)
+		var testCases []models.TestCase
+		if err := json.Unmarshal([]byte(challenge.testCases), &testCases); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO challenges (title, description, difficulty, language, signature)
 			VALUES (?, ?, ?, ?, ?)
-		`, challenge.title, challenge.description, challenge.difficulty, challenge.language, challenge.testCases)
+		`, challenge.title, challenge.description, challenge.difficulty, challenge.language, challenge.signature)
 		if err != nil {
 			return err
 		}
+
+		challengeID, _ := result.LastInsertId()
+		if err := insertTestCases(tx, int(challengeID), testCases); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return tx.Commit()
 }
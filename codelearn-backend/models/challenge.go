@@ -2,13 +2,89 @@ package models
 
 import "time"
 
+// Challenge statuses. Drafts are only visible to their author and admins.
+const (
+	ChallengeDraft     = "draft"
+	ChallengePublished = "published"
+)
+
+// Difficulties a challenge can have.
+var Difficulties = []string{"Easy", "Medium", "Hard"}
+
 type Challenge struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Difficulty  string    `json:"difficulty"`
-	Language    string    `json:"language"`
-	TestCases   string    `json:"test_cases"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Difficulty  string     `json:"difficulty"`
+	Language    string     `json:"language"`
+	Tags        []string   `json:"tags,omitempty"`
+	TestCases   []TestCase `json:"test_cases,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	AuthorID int    `json:"author_id,omitempty"`
+	Status   string `json:"status"`
+	// Revision is the number of the latest ChallengeRevision.
+	Revision int `json:"revision"`
+
+	TimeLimitMS      int `json:"time_limit_ms"`
+	MemoryLimitKB    int `json:"memory_limit_kb"`
+	OutputLimitBytes int `json:"output_limit_bytes"`
+	MaxProcesses     int `json:"max_processes"`
+
+	ScoringPolicy string `json:"scoring_policy"` // binary, proportional, subtask
+
+	Checker          string  `json:"checker"` // exact, whitespace, tokens, float, unordered, json, special
+	CheckerTolerance float64 `json:"checker_tolerance,omitempty"`
+	CheckerLanguage  string  `json:"-"`
+	CheckerCode      string  `json:"-"`
+
+	Signature *Signature `json:"signature,omitempty"`
+	// StarterCode maps a language to the code students start from.
+	StarterCode map[string]string `json:"starter_code,omitempty"`
+
+	// Stats and Solved are only filled in by challenge listings.
+	Stats  *ChallengeStats `json:"stats,omitempty"`
+	Solved *bool           `json:"solved,omitempty"`
+}
+
+// ChallengeStats summarizes the graded submissions to a challenge.
+// Popularity is the number of distinct users who submitted.
+type ChallengeStats struct {
+	Popularity     int     `json:"popularity"`
+	Submissions    int     `json:"submissions"`
+	Accepted       int     `json:"accepted"`
+	AcceptanceRate float64 `json:"acceptance_rate"`
+}
+
+// Tag labels challenges by topic, e.g. "arrays" or "dynamic-programming".
+type Tag struct {
+	Name       string `json:"name"`
+	Challenges int    `json:"challenges"`
+}
+
+// Param is one argument of a Signature. Types are language neutral: int,
+// float, string, bool, optionally followed by one or more [] for arrays,
+// e.g. int[] or string[][].
+type Param struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Signature declares the function students implement. Challenges with a
+// signature take test inputs as comma separated JSON arguments and expect
+// the JSON encoded return value.
+type Signature struct {
+	Function string  `json:"function"`
+	Params   []Param `json:"params"`
+	Returns  string  `json:"returns"`
+}
+
+// Harness overrides the built-in harness template of one language for a
+// challenge.
+type Harness struct {
+	ID          int    `json:"id"`
+	ChallengeID int    `json:"challenge_id"`
+	Language    string `json:"language"`
+	Template    string `json:"template"`
 }
@@ -0,0 +1,102 @@
+package models
+
+import "time"
+
+// Late policies: how submissions made after an assignment's due date count.
+const (
+	// LateAccept counts late submissions in full.
+	LateAccept = "accept"
+	// LatePenalty takes LatePenalty percent off the score of late
+	// submissions.
+	LatePenalty = "penalty"
+	// LateReject gives late submissions no credit.
+	LateReject = "reject"
+)
+
+// Classroom is a group of students enrolled by invite code, run by its
+// owner. InviteCode is only shown to the owner and admins.
+type Classroom struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	OwnerID     int       `json:"owner_id"`
+	Owner       string    `json:"owner"`
+	InviteCode  string    `json:"invite_code,omitempty"`
+	Members     int       `json:"members"`
+	Enrolled    bool      `json:"enrolled"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ClassroomMember struct {
+	UserID   int       `json:"user_id"`
+	Username string    `json:"username"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// Assignment is a set of challenges students of a classroom should solve
+// by DueAt.
+type Assignment struct {
+	ID          int       `json:"id"`
+	ClassroomID int       `json:"classroom_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	DueAt       time.Time `json:"due_at"`
+	LatePolicy  string    `json:"late_policy"`
+	// LatePenalty is the percentage taken off late scores under
+	// LatePenalty.
+	LatePenalty int                   `json:"late_penalty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	Challenges  []AssignmentChallenge `json:"challenges"`
+}
+
+// Credit is what a submission scored at time at counts for under the late
+// policy, and whether it was late.
+func (a Assignment) Credit(score int, at time.Time) (int, bool) {
+	if !at.After(a.DueAt) {
+		return score, false
+	}
+	switch a.LatePolicy {
+	case LateReject:
+		return 0, true
+	case LatePenalty:
+		return score * (100 - a.LatePenalty) / 100, true
+	}
+	return score, true
+}
+
+type AssignmentChallenge struct {
+	ChallengeID int    `json:"challenge_id"`
+	Title       string `json:"title"`
+}
+
+// AssignmentResults is the instructor's view of an assignment: a row per
+// enrolled student.
+type AssignmentResults struct {
+	Assignment Assignment      `json:"assignment"`
+	Students   []StudentResult `json:"students"`
+}
+
+// StudentResult is a student's progress on an assignment. Credit adds up
+// the credit of their best submission to each challenge; Completed counts
+// the challenges they passed with a submission earning credit.
+type StudentResult struct {
+	UserID     int               `json:"user_id"`
+	Username   string            `json:"username"`
+	Credit     int               `json:"credit"`
+	Completed  int               `json:"completed"`
+	Challenges []ChallengeResult `json:"challenges"`
+}
+
+// ChallengeResult is a student's best submission to an assignment
+// challenge: the one earning the most credit, the earliest among equals.
+// SubmissionID is 0 if they have no judged submission.
+type ChallengeResult struct {
+	ChallengeID  int        `json:"challenge_id"`
+	SubmissionID int        `json:"submission_id,omitempty"`
+	Status       string     `json:"status,omitempty"`
+	Score        int        `json:"score"`
+	Credit       int        `json:"credit"`
+	Late         bool       `json:"late"`
+	Attempts     int        `json:"attempts"`
+	SubmittedAt  *time.Time `json:"submitted_at,omitempty"`
+}
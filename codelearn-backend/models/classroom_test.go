@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignmentCredit(t *testing.T) {
+	due := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		policy   string
+		penalty  int
+		score    int
+		at       time.Time
+		want     int
+		wantLate bool
+	}{
+		{"on time", LateReject, 0, 80, due.Add(-time.Hour), 80, false},
+		{"at the due date", LateReject, 0, 80, due, 80, false},
+		{"late accepted", LateAccept, 0, 80, due.Add(time.Second), 80, true},
+		{"late rejected", LateReject, 0, 80, due.Add(time.Second), 0, true},
+		{"late penalty", LatePenalty, 25, 80, due.Add(time.Second), 60, true},
+		{"late penalty rounds down", LatePenalty, 30, 99, due.Add(time.Second), 69, true},
+		{"full penalty", LatePenalty, 100, 80, due.Add(time.Second), 0, true},
+		{"penalty on time", LatePenalty, 25, 80, due, 80, false},
+		{"no policy accepts", "", 0, 80, due.Add(time.Second), 80, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Assignment{DueAt: due, LatePolicy: tt.policy, LatePenalty: tt.penalty}
+			got, late := a.Credit(tt.score, tt.at)
+			if got != tt.want || late != tt.wantLate {
+				t.Errorf("Credit(%d) = %d, %v, want %d, %v", tt.score, got, late, tt.want, tt.wantLate)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package models
+
+import "time"
+
+// Contest scoring modes.
+const (
+	// ContestICPC ranks by problems solved, then by penalty time: the
+	// minutes until each accepted submission plus PenaltyMinutes per
+	// rejected attempt before it.
+	ContestICPC = "icpc"
+	// ContestPoints ranks by the sum of the best score on each problem,
+	// scaled to its points, then by the time of the last improvement.
+	ContestPoints = "points"
+)
+
+// Contest phases, derived from the current time.
+const (
+	ContestUpcoming = "upcoming"
+	ContestRunning  = "running"
+	ContestEnded    = "ended"
+)
+
+type Contest struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Scoring     string    `json:"scoring"`
+	// PenaltyMinutes is added per rejected attempt under ContestICPC.
+	PenaltyMinutes int `json:"penalty_minutes"`
+	// FreezeMinutes is how long before the end the public scoreboard
+	// stops showing new results. Zero disables the freeze.
+	FreezeMinutes int       `json:"freeze_minutes"`
+	CreatedBy     int       `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	Phase string `json:"phase"`
+	// Participants counts those who took part live.
+	Participants int  `json:"participants"`
+	Registered   bool `json:"registered"`
+	// VirtualStart is when the caller started a virtual run of the
+	// contest after it ended, if they did.
+	VirtualStart *time.Time         `json:"virtual_start,omitempty"`
+	Challenges   []ContestChallenge `json:"challenges,omitempty"`
+}
+
+// Duration is how long the contest runs.
+func (c Contest) Duration() time.Duration {
+	return c.EndTime.Sub(c.StartTime)
+}
+
+// PhaseAt is the phase of the contest at now.
+func (c Contest) PhaseAt(now time.Time) string {
+	switch {
+	case now.Before(c.StartTime):
+		return ContestUpcoming
+	case now.Before(c.EndTime):
+		return ContestRunning
+	}
+	return ContestEnded
+}
+
+// OpenAt reports whether the caller can submit at now: while the contest
+// runs, or during their virtual run, which lasts as long as the contest.
+func (c Contest) OpenAt(now time.Time) bool {
+	if c.VirtualStart != nil {
+		return now.Before(c.VirtualStart.Add(c.Duration()))
+	}
+	return c.PhaseAt(now) == ContestRunning
+}
+
+// ContestChallenge is a problem of a contest, labelled A, B, C... in order.
+type ContestChallenge struct {
+	ChallengeID int    `json:"challenge_id"`
+	Label       string `json:"label"`
+	Title       string `json:"title,omitempty"`
+	Points      int    `json:"points"`
+}
+
+// ContestSubmission is a submission as the scoreboard sees it.
+type ContestSubmission struct {
+	UserID      int
+	ChallengeID int
+	Status      string
+	Score       int
+	CreatedAt   time.Time
+}
+
+// ContestParticipant is a registered user. Start is when their clock
+// started: the contest's start time for everyone taking part live, or the
+// start of their run for virtual participants.
+type ContestParticipant struct {
+	UserID   int
+	Username string
+	Start    time.Time
+	Virtual  bool
+}
+
+type Scoreboard struct {
+	ContestID int    `json:"contest_id"`
+	Scoring   string `json:"scoring"`
+	// Frozen is set when results of submissions made after FrozenAt are
+	// hidden. Such submissions are counted as pending.
+	Frozen   bool               `json:"frozen"`
+	FrozenAt *time.Time         `json:"frozen_at,omitempty"`
+	Problems []ContestChallenge `json:"problems"`
+	Rows     []ScoreboardRow    `json:"rows"`
+}
+
+// ScoreboardRow is one participant's standing. Penalty is the ICPC penalty
+// in minutes, or under ContestPoints the minute of the last score
+// improvement. Virtual rows are ghosts: they are placed where they would
+// have ranked without moving anyone who took part live.
+type ScoreboardRow struct {
+	Rank     int             `json:"rank"`
+	UserID   int             `json:"-"`
+	Username string          `json:"username"`
+	Virtual  bool            `json:"virtual,omitempty"`
+	Solved   int             `json:"solved"`
+	Points   int             `json:"points"`
+	Penalty  int             `json:"penalty"`
+	Problems []ProblemResult `json:"problems"`
+}
+
+// ProblemResult is a participant's result on one problem. Attempts counts
+// the judged submissions up to and including the first accepted one;
+// Minute is when the problem was solved, or reached its best score.
+type ProblemResult struct {
+	Label    string `json:"label"`
+	Solved   bool   `json:"solved"`
+	Attempts int    `json:"attempts"`
+	Pending  int    `json:"pending"`
+	Score    int    `json:"score"`
+	Points   int    `json:"points"`
+	Minute   int    `json:"minute"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// TimestampLayout is how SQLite's CURRENT_TIMESTAMP stores times, and how
+// leaderboards report them.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+// LeaderboardEntry is a user's standing. TotalScore sums the user's best
+// score on each challenge, and AchievedAt is when the last of those best
+// scores was first reached, which breaks ties.
+type LeaderboardEntry struct {
+	Rank         int    `json:"rank"`
+	UserID       int    `json:"-"`
+	Username     string `json:"username"`
+	TotalScore   int    `json:"total_score"`
+	Challenges   int    `json:"challenges"`
+	Solved       int    `json:"solved"`
+	Submissions  int    `json:"submissions"`
+	AchievedAt   string `json:"achieved_at"`
+	LastActivity string `json:"last_activity"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Rejudge is a batch of historical submissions sent back through the
+// judge, either every finished submission to a challenge or a single one.
+type Rejudge struct {
+	ID           int       `json:"id"`
+	ChallengeID  *int      `json:"challenge_id,omitempty"`
+	SubmissionID *int      `json:"submission_id,omitempty"`
+	RequestedBy  int       `json:"requested_by"`
+	Total        int       `json:"total"`
+	Judged       int       `json:"judged"`
+	Changed      int       `json:"changed"`
+	Finished     bool      `json:"finished"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	Items []RejudgeItem `json:"items,omitempty"`
+}
+
+// RejudgeItem records the verdict a submission had before a rejudge and,
+// once graded again, the one that replaced it.
+type RejudgeItem struct {
+	SubmissionID   int        `json:"submission_id"`
+	PreviousStatus string     `json:"previous_status"`
+	PreviousScore  int        `json:"previous_score"`
+	NewStatus      *string    `json:"new_status"`
+	NewScore       *int       `json:"new_score"`
+	JudgedAt       *time.Time `json:"judged_at"`
+}
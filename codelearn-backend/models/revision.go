@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// ChallengeSnapshot is the editable content of a challenge. It is what
+// authors send to create or replace a challenge and what each revision
+// stores, so unlike Challenge it includes the special checker's code.
+type ChallengeSnapshot struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Difficulty  string `json:"difficulty"`
+	Language    string `json:"language"`
+	// Tags are lowercase and sorted.
+	Tags []string `json:"tags,omitempty"`
+
+	TimeLimitMS      int `json:"time_limit_ms"`
+	MemoryLimitKB    int `json:"memory_limit_kb"`
+	OutputLimitBytes int `json:"output_limit_bytes"`
+	MaxProcesses     int `json:"max_processes"`
+
+	ScoringPolicy    string  `json:"scoring_policy"`
+	Checker          string  `json:"checker"`
+	CheckerTolerance float64 `json:"checker_tolerance"`
+	CheckerLanguage  string  `json:"checker_language"`
+	CheckerCode      string  `json:"checker_code"`
+
+	Signature *Signature `json:"signature"`
+	TestCases []TestCase `json:"test_cases"`
+
+	// StarterCode and Harnesses map a language to the code students start
+	// from and to a harness template overriding the built-in one.
+	StarterCode map[string]string `json:"starter_code,omitempty"`
+	Harnesses   map[string]string `json:"harnesses,omitempty"`
+}
+
+// ChallengeRevision is an immutable snapshot of a challenge taken on every
+// edit. Revisions are numbered from 1 per challenge.
+type ChallengeRevision struct {
+	ID          int                `json:"id"`
+	ChallengeID int                `json:"challenge_id"`
+	Revision    int                `json:"revision"`
+	EditorID    int                `json:"editor_id,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Snapshot    *ChallengeSnapshot `json:"snapshot,omitempty"`
+}
@@ -8,8 +8,54 @@ type Submission struct {
 	ChallengeID int       `json:"challenge_id"`
 	Code        string    `json:"code"`
 	Language    string    `json:"language"`
-	Status      string    `json:"status"` // pending, passed, failed
+	Status      string    `json:"status"` // pending, passed or a judge failure verdict
 	Score       int       `json:"score"`
 	Output      string    `json:"output"`
 	CreatedAt   time.Time `json:"created_at"`
+	// ChallengeRevision is the revision of the challenge the submission was
+	// last judged against, 0 until it has been graded.
+	ChallengeRevision int `json:"challenge_revision"`
+	// ContestID is the contest the submission was made in, 0 if none.
+	ContestID int `json:"contest_id,omitempty"`
+
+	Results []SubmissionResult `json:"results,omitempty"`
+	Groups  []SubmissionGroup  `json:"groups,omitempty"`
+}
+
+// SubmissionGroup is the score breakdown of one subtask group.
+type SubmissionGroup struct {
+	Group     string `json:"group"`
+	Passed    int    `json:"passed"`
+	Total     int    `json:"total"`
+	Points    int    `json:"points"`
+	MaxPoints int    `json:"max_points"`
+}
+
+// SubmissionResult is the outcome of one test case. Input, Expected, Output,
+// Stderr and MemoryKB are cleared before hidden cases are returned to
+// students.
+type SubmissionResult struct {
+	ID           int    `json:"-"`
+	SubmissionID int    `json:"-"`
+	Index        int    `json:"index"`
+	Verdict      string `json:"verdict"`
+	TimeMS       int64  `json:"time_ms"`
+	MemoryKB     int64  `json:"memory_kb,omitempty"`
+	Hidden       bool   `json:"hidden"`
+	Input        string `json:"input,omitempty"`
+	Expected     string `json:"expected,omitempty"`
+	Output       string `json:"output,omitempty"`
+	Stderr       string `json:"stderr,omitempty"`
+}
+
+// Redact strips everything but the verdict and timing from hidden results.
+func (r *SubmissionResult) Redact() {
+	if !r.Hidden {
+		return
+	}
+	r.MemoryKB = 0
+	r.Input = ""
+	r.Expected = ""
+	r.Output = ""
+	r.Stderr = ""
 }
@@ -0,0 +1,16 @@
+package models
+
+// TestCase is one input/expected output pair of a challenge. Hidden cases
+// are graded but never shown to students. Group names the subtask the case
+// belongs to. A zero TimeLimitMS means the challenge's time limit applies.
+type TestCase struct {
+	ID          int    `json:"id"`
+	ChallengeID int    `json:"challenge_id"`
+	Position    int    `json:"position"`
+	Input       string `json:"input"`
+	Expected    string `json:"expected"`
+	Hidden      bool   `json:"hidden"`
+	Weight      int    `json:"weight"`
+	Group       string `json:"group,omitempty"`
+	TimeLimitMS int    `json:"time_limit_ms,omitempty"`
+}
@@ -2,11 +2,28 @@ package models
 
 import "time"
 
+// Roles, from least to most privileged. Authors can manage challenges;
+// admins can also manage users and rejudge submissions.
+const (
+	RoleStudent = "student"
+	RoleAuthor  = "author"
+	RoleAdmin   = "admin"
+)
+
+func ValidRole(role string) bool {
+	switch role {
+	case RoleStudent, RoleAuthor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	ID        int       `json:"id"`
 	Username  string    `json:"username"`
 	Email     string    `json:"email"`
 	Password  string    `json:"-"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
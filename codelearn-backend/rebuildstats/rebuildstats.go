@@ -0,0 +1,27 @@
+// Command rebuildstats recomputes user_stats from the submissions, to
+// repair the materialized leaderboard if it ever drifts:
+//
+//	go run rebuildstats/rebuildstats.go
+package main
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/stats"
+	"log"
+)
+
+func main() {
+	if err := db.InitDB(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := stats.Rebuild(); err != nil {
+		log.Fatal("Failed to rebuild user stats: ", err)
+	}
+
+	var users int
+	if err := db.DB.QueryRow("SELECT COUNT(*) FROM user_stats").Scan(&users); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Rebuilt stats of %d users", users)
+}
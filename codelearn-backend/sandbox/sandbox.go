@@ -0,0 +1,16 @@
+// Command sandbox is the helper the judge runs every untrusted program
+// through. It is a binary of its own, rather than the server re-executing
+// itself, so that none of the server's initialisation, such as loading
+// .env, runs before the program is isolated:
+//
+//	go build -o codelearn-sandbox ./sandbox
+//
+// The server looks for it next to its own executable, in PATH or at
+// JUDGE_SANDBOX.
+package main
+
+import "codelearn-backend/judge"
+
+func main() {
+	judge.SandboxMain()
+}
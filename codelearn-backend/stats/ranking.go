@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/models"
+	"sync"
+	"time"
+)
+
+// ranking caches the global leaderboard. Writers call Invalidate after
+// committing a change to user_stats; the next read reloads it.
+var ranking struct {
+	sync.Mutex
+	// generation counts invalidations, so a load that raced with one is
+	// not cached.
+	generation int
+	entries    []models.LeaderboardEntry
+	byUser     map[int]int
+}
+
+// Invalidate drops the cached ranking.
+func Invalidate() {
+	ranking.Lock()
+	defer ranking.Unlock()
+	ranking.generation++
+	ranking.entries = nil
+	ranking.byUser = nil
+}
+
+// Leaderboard returns the top limit users of the global leaderboard and the
+// entry of userID, nil if the user has no counted submission.
+func Leaderboard(limit, userID int) ([]models.LeaderboardEntry, *models.LeaderboardEntry, error) {
+	entries, byUser, err := cached()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if limit > len(entries) {
+		limit = len(entries)
+	}
+	top := append([]models.LeaderboardEntry{}, entries[:limit]...)
+
+	var me *models.LeaderboardEntry
+	if i, ok := byUser[userID]; ok {
+		entry := entries[i]
+		me = &entry
+	}
+	return top, me, nil
+}
+
+func cached() ([]models.LeaderboardEntry, map[int]int, error) {
+	ranking.Lock()
+	if ranking.entries != nil {
+		defer ranking.Unlock()
+		return ranking.entries, ranking.byUser, nil
+	}
+	generation := ranking.generation
+	ranking.Unlock()
+
+	entries, err := load()
+	if err != nil {
+		return nil, nil, err
+	}
+	byUser := make(map[int]int, len(entries))
+	for i, e := range entries {
+		byUser[e.UserID] = i
+	}
+
+	ranking.Lock()
+	defer ranking.Unlock()
+	if ranking.generation == generation {
+		ranking.entries, ranking.byUser = entries, byUser
+	}
+	return entries, byUser, nil
+}
+
+func load() ([]models.LeaderboardEntry, error) {
+	rows, err := db.DB.Query(`
+		SELECT s.user_id, u.username, s.total_score, s.challenges, s.solved, s.submissions,
+		       s.achieved_at, s.last_activity
+		FROM user_stats s JOIN users u ON u.id = s.user_id
+		ORDER BY s.total_score DESC, s.achieved_at ASC, s.user_id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.LeaderboardEntry{}
+	for rows.Next() {
+		e := models.LeaderboardEntry{Rank: len(entries) + 1}
+		var achievedAt, lastActivity time.Time
+		err := rows.Scan(&e.UserID, &e.Username, &e.TotalScore, &e.Challenges, &e.Solved, &e.Submissions,
+			&achievedAt, &lastActivity)
+		if err != nil {
+			return nil, err
+		}
+		e.AchievedAt = achievedAt.Format(models.TimestampLayout)
+		e.LastActivity = lastActivity.Format(models.TimestampLayout)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
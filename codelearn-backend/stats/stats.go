@@ -0,0 +1,120 @@
+// Package stats maintains user_stats, the materialized global leaderboard,
+// and an in-memory ranking built from it.
+//
+// Only graded submissions to published, undeleted challenges made outside
+// contests count. For each user and challenge, user_best_scores keeps the best
+// score, whether the challenge was solved and when the best score was
+// first reached; user_stats sums those per user. Both are recomputed for
+// the affected users and challenges in the transaction that changes a
+// verdict or a challenge's status or deletes it, and Rebuild recomputes
+// everything.
+package stats
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/judge"
+	"codelearn-backend/models"
+	"database/sql"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// RefreshSubmission updates the stats of the user who made a submission
+// after it was graded.
+func RefreshSubmission(q querier, submissionID int) error {
+	var userID, challengeID int
+	err := q.QueryRow("SELECT user_id, challenge_id FROM submissions WHERE id = ?", submissionID).
+		Scan(&userID, &challengeID)
+	if err != nil {
+		return err
+	}
+	return refresh(q, "user_id = ? AND challenge_id = ?", userID, challengeID)
+}
+
+// RefreshChallenge updates the stats of everyone who submitted to a
+// challenge, after it was published, unpublished or deleted.
+func RefreshChallenge(q querier, challengeID int) error {
+	return refresh(q, "challenge_id = ?", challengeID)
+}
+
+// Rebuild recomputes the stats of every user from their submissions.
+func Rebuild() error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := refresh(tx, "1 = 1"); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	Invalidate()
+	return nil
+}
+
+// refresh recomputes the best scores of the user and challenge pairs
+// matching cond, a condition on the user_id and challenge_id columns, and
+// the totals of the users involved.
+func refresh(q querier, cond string, args ...interface{}) error {
+	counted := []interface{}{judge.StatusPending, judge.StatusRunning, models.ChallengePublished}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM user_best_scores WHERE " + cond, args},
+		{`
+			INSERT INTO user_best_scores (user_id, challenge_id, score, solved, achieved_at)
+			WITH counted AS (
+				SELECT s.user_id, s.challenge_id, s.score, s.status, s.created_at
+				FROM submissions s JOIN challenges c ON c.id = s.challenge_id
+				WHERE s.status NOT IN (?, ?) AND c.status = ? AND c.deleted_at IS NULL AND s.contest_id IS NULL AND ` + cond + `
+			),
+			best AS (
+				SELECT user_id, challenge_id, MAX(score) AS score, MAX(status = ?) AS solved
+				FROM counted GROUP BY user_id, challenge_id
+			)
+			SELECT b.user_id, b.challenge_id, b.score, b.solved, MIN(k.created_at)
+			FROM best b JOIN counted k
+			  ON k.user_id = b.user_id AND k.challenge_id = b.challenge_id AND k.score = b.score
+			GROUP BY b.user_id, b.challenge_id
+		`, concat(counted, args, []interface{}{judge.StatusPassed})},
+		{"DELETE FROM user_stats WHERE user_id IN (SELECT user_id FROM submissions WHERE " + cond + ")", args},
+		{`
+			INSERT INTO user_stats (user_id, total_score, challenges, solved, submissions, achieved_at, last_activity)
+			SELECT b.user_id, SUM(b.score), COUNT(*), SUM(b.solved), a.submissions, MAX(b.achieved_at), a.last_activity
+			FROM user_best_scores b
+			JOIN (
+				SELECT s.user_id, COUNT(*) AS submissions, MAX(s.created_at) AS last_activity
+				FROM submissions s JOIN challenges c ON c.id = s.challenge_id
+				WHERE s.status NOT IN (?, ?) AND c.status = ? AND c.deleted_at IS NULL AND s.contest_id IS NULL
+				  AND s.user_id IN (SELECT user_id FROM submissions WHERE ` + cond + `)
+				GROUP BY s.user_id
+			) a ON a.user_id = b.user_id
+			GROUP BY b.user_id
+		`, concat(counted, args)},
+	}
+
+	for _, stmt := range statements {
+		if _, err := q.Exec(stmt.query, stmt.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func concat(lists ...[]interface{}) []interface{} {
+	var all []interface{}
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	return all
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/models"
+	"errors"
+	"os"
+)
+
+// ErrBootstrapAdminMissing is returned by BootstrapAdmin when the user
+// named by BOOTSTRAP_ADMIN has not registered yet.
+var ErrBootstrapAdminMissing = errors.New("bootstrap admin user does not exist")
+
+// CurrentRole is the role a user has now, which may differ from the one in
+// a token issued before an admin changed it. It returns sql.ErrNoRows if
+// the user no longer exists.
+func CurrentRole(userID int) (string, error) {
+	var role string
+	err := db.DB.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+	return role, err
+}
+
+// BootstrapAdmin promotes the user named by the BOOTSTRAP_ADMIN environment
+// variable to admin as long as no admin exists yet, so the first admin can
+// be created without database access. It is only run at startup and only
+// promotes an existing account: doing it on registration would make
+// whoever first claims the name an admin. It reports whether a user was
+// promoted.
+func BootstrapAdmin() (bool, error) {
+	username := os.Getenv("BOOTSTRAP_ADMIN")
+	if username == "" {
+		return false, nil
+	}
+
+	var exists bool
+	if err := db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)", username).Scan(&exists); err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, ErrBootstrapAdminMissing
+	}
+
+	result, err := db.DB.Exec(`
+		UPDATE users SET role = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE username = ? AND NOT EXISTS (SELECT 1 FROM users WHERE role = ?)
+	`, models.RoleAdmin, username, models.RoleAdmin)
+	if err != nil {
+		return false, err
+	}
+	promoted, err := result.RowsAffected()
+	return promoted > 0, err
+}
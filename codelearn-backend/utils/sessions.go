@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"codelearn-backend/auth"
+	"codelearn-backend/db"
+	"codelearn-backend/models"
+	"database/sql"
+	"time"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// CreateSession records a login without refresh tokens, such as a long
+// lived CLI token, and returns its ID.
+func CreateSession(userID int, userAgent, ip string, expiresAt time.Time) (string, error) {
+	return createSession(db.DB, userID, userAgent, ip, expiresAt)
+}
+
+func createSession(e execer, userID int, userAgent, ip string, expiresAt time.Time) (string, error) {
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = e.Exec(`
+		INSERT INTO sessions (id, user_id, user_agent, ip_address, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, id, userID, userAgent, ip, expiresAt.UTC())
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// ListSessions returns the user's sessions that are neither revoked nor
+// expired, most recently used first.
+func ListSessions(userID int) ([]models.Session, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL
+		ORDER BY last_used_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if s.ExpiresAt.After(now) {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession ends one of the user's sessions and reports whether it
+// existed.
+func RevokeSession(userID int, sessionID string) (bool, error) {
+	var exists bool
+	err := db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM sessions WHERE id = ? AND user_id = ? AND revoked_at IS NULL)",
+		sessionID, userID).Scan(&exists)
+	if err != nil || !exists {
+		return false, err
+	}
+	return true, revokeSession(db.DB, sessionID)
+}
+
+// RevokeAllSessions ends every session of the user.
+func RevokeAllSessions(userID int) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE revoked_at IS NULL AND family_id IN (SELECT id FROM sessions WHERE user_id = ?)
+	`, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = ? AND revoked_at IS NULL", userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func revokeSession(e execer, sessionID string) error {
+	_, err := e.Exec("UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = ? AND revoked_at IS NULL", sessionID)
+	if err != nil {
+		return err
+	}
+	_, err = e.Exec("UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL", sessionID)
+	return err
+}
+
+// RevokeToken adds a single token to the revocation list until it expires.
+func RevokeToken(tokenID string, expiresAt time.Time) error {
+	// Expired tokens fail validation anyway, so their entries can go.
+	if _, err := db.DB.Exec("DELETE FROM revoked_tokens WHERE expires_at < ?", time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := db.DB.Exec("INSERT OR IGNORE INTO revoked_tokens (jti, expires_at) VALUES (?, ?)", tokenID, expiresAt.UTC())
+	return err
+}
+
+// IsRevoked reports whether a token has been revoked, either by itself or
+// through its session.
+func IsRevoked(claims *auth.Claims) (bool, error) {
+	var revoked bool
+	err := db.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = ?)
+			OR NOT EXISTS(SELECT 1 FROM sessions WHERE id = ? AND revoked_at IS NULL)
+	`, claims.ID, claims.SessionID).Scan(&revoked)
+	return revoked, err
+}
@@ -1,58 +1,193 @@
 package utils
 
 import (
-	"codelearn-backend/initialisers"
+	"codelearn-backend/auth"
+	"codelearn-backend/db"
 	"codelearn-backend/models"
-	"os"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret []byte
+const (
+	accessTokenTTL  = time.Hour
+	refreshTokenTTL = 7 * 24 * time.Hour
+)
 
-type Claims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	jwt.RegisteredClaims
-}
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused means an already rotated refresh token was
+	// presented again. Its whole family has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// GenerateTokens starts a new session for the device identified by
+// userAgent and ip, as on login, and mints its first access and refresh
+// token. The refresh tokens of a session form one rotation family.
+func GenerateTokens(user models.User, userAgent, ip string) (string, string, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+
+	family, err := createSession(tx, user.ID, userAgent, ip, time.Now().Add(refreshTokenTTL))
+	if err != nil {
+		return "", "", err
+	}
 
-func init() {
-	initialisers.LoadEnv()
+	accessToken, refreshToken, err := issueTokens(tx, user, family)
+	if err != nil {
+		return "", "", err
+	}
 
-	jwtSecret = []byte(os.Getenv("JWT_SECRET_KEY"))
+	return accessToken, refreshToken, tx.Commit()
 }
 
-func GenerateTokens(user models.User) (string, string, error) {
-	accessClaims := &Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// RotateRefreshToken exchanges a refresh token for a new access and refresh
+// token in the same family. Each refresh token can be used once; presenting
+// a used one again revokes every token of its family, since either the
+// client or an attacker holds a stolen copy.
+func RotateRefreshToken(refreshToken string) (models.User, string, string, error) {
+	var user models.User
+
+	claims, err := auth.Verify(refreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		return user, "", "", ErrInvalidRefreshToken
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return user, "", "", err
 	}
+	defer tx.Rollback()
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString(jwtSecret)
+	var id int
+	var family string
+	var used, revoked bool
+	err = tx.QueryRow(`
+		SELECT t.id, t.family_id, t.used_at IS NOT NULL, t.revoked_at IS NOT NULL OR s.revoked_at IS NOT NULL
+		FROM refresh_tokens t
+		JOIN sessions s ON s.id = t.family_id
+		WHERE t.token_hash = ?
+	`, hashToken(refreshToken)).Scan(&id, &family, &used, &revoked)
+	if err == sql.ErrNoRows {
+		return user, "", "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return user, "", "", err
+	}
+	if revoked {
+		return user, "", "", ErrInvalidRefreshToken
+	}
+
+	result, err := tx.Exec("UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = ? AND used_at IS NULL", id)
+	if err != nil {
+		return user, "", "", err
+	}
+	// A concurrent rotation of the same token counts as reuse as well.
+	if claimed, _ := result.RowsAffected(); used || claimed == 0 {
+		if err := revokeSession(tx, family); err != nil {
+			return user, "", "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return user, "", "", err
+		}
+		return user, "", "", ErrRefreshTokenReused
+	}
+
+	err = tx.QueryRow(`
+		SELECT id, username, email, role, created_at, updated_at
+		FROM users WHERE id = ?
+	`, claims.UserID).Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return user, "", "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return user, "", "", err
+	}
+
+	accessToken, newRefreshToken, err := issueTokens(tx, user, family)
+	if err != nil {
+		return user, "", "", err
+	}
+
+	_, err = tx.Exec("UPDATE sessions SET last_used_at = CURRENT_TIMESTAMP, expires_at = ? WHERE id = ?",
+		time.Now().Add(refreshTokenTTL).UTC(), family)
+	if err != nil {
+		return user, "", "", err
+	}
+
+	return user, accessToken, newRefreshToken, tx.Commit()
+}
+
+// issueTokens signs a token pair and stores the hash of the refresh token
+// in the given family.
+func issueTokens(tx *sql.Tx, user models.User, family string) (string, string, error) {
+	now := time.Now()
+
+	accessToken, err := SignToken(user, auth.TokenTypeAccess, family, now, accessTokenTTL)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshClaims := &Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+	refreshToken, err := SignToken(user, auth.TokenTypeRefresh, family, now, refreshTokenTTL)
+	if err != nil {
+		return "", "", err
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString(jwtSecret)
+	_, err = tx.Exec(`
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, user.ID, family, hashToken(refreshToken), now.Add(refreshTokenTTL).UTC())
 	if err != nil {
 		return "", "", err
 	}
 
-	return accessTokenString, refreshTokenString, nil
+	return accessToken, refreshToken, nil
+}
+
+// SignToken signs a token of the given type for a session. Every token gets
+// a random ID (jti), which also keeps tokens minted for the same user in the
+// same second distinct.
+func SignToken(user models.User, typ, sessionID string, now time.Time, ttl time.Duration) (string, error) {
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	claims := &auth.Claims{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		Type:      typ,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	return auth.Sign(claims)
+}
+
+// hashToken is how refresh tokens are stored, so a leaked database does not
+// leak usable tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"codelearn-backend/db"
+	"codelearn-backend/models"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testSchema holds the tables token rotation touches, as created by the
+// migration.
+const testSchema = `
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT UNIQUE NOT NULL,
+	email TEXT UNIQUE NOT NULL,
+	password TEXT NOT NULL,
+	role TEXT DEFAULT 'student',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE refresh_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	family_id TEXT NOT NULL,
+	token_hash TEXT UNIQUE NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME,
+	revoked_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE sessions (
+	id TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	user_agent TEXT DEFAULT '',
+	ip_address TEXT DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_used_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	expires_at DATETIME NOT NULL,
+	revoked_at DATETIME
+);
+INSERT INTO users (username, email, password) VALUES ('alice', 'alice@example.com', '');
+`
+
+func TestMain(m *testing.M) {
+	os.Setenv("JWT_SECRET_KEY", "test-secret")
+
+	dir, err := os.MkdirTemp("", "codelearn-utils-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	db.DB, err = sql.Open("sqlite3", filepath.Join(dir, "test.db")+"?_busy_timeout=5000")
+	if err == nil {
+		_, err = db.DB.Exec(testSchema)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	db.DB.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func login(t *testing.T) (string, string) {
+	t.Helper()
+	access, refresh, err := GenerateTokens(models.User{ID: 1, Username: "alice"}, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return access, refresh
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	_, first := login(t)
+
+	user, _, second, err := RotateRefreshToken(first)
+	if err != nil {
+		t.Fatalf("first rotation: %v", err)
+	}
+	if user.Username != "alice" {
+		t.Errorf("user = %+v, want alice", user)
+	}
+	if second == first {
+		t.Fatal("rotation returned the same refresh token")
+	}
+
+	_, _, third, err := RotateRefreshToken(second)
+	if err != nil {
+		t.Fatalf("second rotation: %v", err)
+	}
+
+	_, otherSession := login(t)
+
+	if _, _, _, err := RotateRefreshToken(first); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reusing a rotated token: err = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, _, _, err := RotateRefreshToken(third); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("latest token of a revoked family: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, _, _, err := RotateRefreshToken(otherSession); err != nil {
+		t.Errorf("token of another login: %v", err)
+	}
+}
+
+func TestRotateRefreshTokenRejects(t *testing.T) {
+	access, _ := login(t)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not-a-token"},
+		{"access token", access},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := RotateRefreshToken(tt.token); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("err = %v, want ErrInvalidRefreshToken", err)
+			}
+		})
+	}
+}
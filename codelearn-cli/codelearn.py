@@ -6,6 +6,7 @@ CodeLearn CLI - Submit your code solutions directly from your local environment
 import os
 import sys
 import json
+import time
 import requests
 import argparse
 from pathlib import Path
@@ -188,12 +189,18 @@ class CodeLearnCLI:
                                        "language": language
                                    })
             
-            if response.status_code == 201:
+            if response.status_code == 202:
                 data = response.json()
-                submission = data["submission"]
+                submission_id = data["submission_id"]
                 
                 print(f"\n✅ Solution submitted successfully!")
-                print(f"Submission ID: {submission['id']}")
+                print(f"Submission ID: {submission_id}")
+                print("⏳ Waiting for grading...")
+                
+                submission = self.wait_for_submission(submission_id, headers)
+                if not submission:
+                    return
+                
                 print(f"Status: {submission['status']}")
                 print(f"Score: {submission['score']}/100")
                 print(f"Output: {submission['output']}")
@@ -202,6 +209,53 @@ class CodeLearnCLI:
         except Exception as e:
             print(f"❌ Error submitting solution: {e}")
     
+    def wait_for_submission(self, submission_id, headers, timeout=120):
+        """Follow grading progress until the judge has finished the submission"""
+        try:
+            self.stream_submission_events(submission_id, headers, timeout)
+        except requests.RequestException:
+            pass
+        return self.poll_submission(submission_id, headers, timeout)
+    
+    def stream_submission_events(self, submission_id, headers, timeout):
+        """Print server-sent grading events until the final verdict arrives"""
+        response = requests.get(f"{API_BASE_URL}/submissions/{submission_id}/events",
+                                headers=headers, stream=True, timeout=timeout)
+        if response.status_code != 200:
+            return
+        
+        event = None
+        for line in response.iter_lines(decode_unicode=True):
+            if line.startswith("event:"):
+                event = line[len("event:"):].strip()
+            elif line.startswith("data:"):
+                data = json.loads(line[len("data:"):])
+                if event == "compiling":
+                    print("🔨 Compiling...")
+                elif event == "test":
+                    icon = "✅" if data["verdict"] == "passed" else "❌"
+                    print(f"  {icon} Test {data['test']}/{data['total']}: {data['verdict']} ({data.get('time_ms', 0)} ms)")
+                elif event == "verdict":
+                    response.close()
+                    return
+    
+    def poll_submission(self, submission_id, headers, timeout):
+        """Poll a submission until the judge has finished grading it"""
+        deadline = time.time() + timeout
+        while time.time() < deadline:
+            response = requests.get(f"{API_BASE_URL}/submissions/{submission_id}", headers=headers)
+            if response.status_code != 200:
+                print(f"❌ Failed to fetch submission: {response.json().get('error', 'Unknown error')}")
+                return None
+            
+            submission = response.json()
+            if submission["status"] not in ("pending", "running"):
+                return submission
+            time.sleep(1)
+        
+        print("⌛ Still grading. Check later with 'codelearn submissions'.")
+        return None
+    
     def list_submissions(self):
         """List user's submissions"""
         headers = self.get_headers()